@@ -0,0 +1,11 @@
+package cache
+
+import "time"
+
+//Locker 分布式锁，多个实例共用同一个AppID时，用于保证同一时间只有一个实例刷新access_token、jsapi_ticket等
+type Locker interface {
+	//Acquire 尝试获取锁，value用于标识持有者，获取成功返回true，锁在ttl后自动释放
+	Acquire(key string, value string, ttl time.Duration) (bool, error)
+	//Release 释放锁，仅当锁仍由value持有时才会删除
+	Release(key string, value string) error
+}
@@ -70,3 +70,41 @@ func (mem *Memcache) SetString(key string, val string, timeout time.Duration) (e
 func (mem *Memcache) Delete(key string) error {
 	return mem.conn.Delete(key)
 }
+
+//Acquire 使用 Add 获取锁，key已存在时返回false
+func (mem *Memcache) Acquire(key string, value string, ttl time.Duration) (bool, error) {
+	expiration := int32(ttl / time.Second)
+	if expiration < 1 {
+		expiration = 1
+	}
+	err := mem.conn.Add(&memcache.Item{Key: key, Value: []byte(value), Expiration: expiration})
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+//Release 释放锁
+//使用CAS将仍由value持有的锁置为立即过期，避免锁在Get之后过期并被其他实例获取时误删对方的锁
+func (mem *Memcache) Release(key string, value string) error {
+	item, err := mem.conn.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if string(item.Value) != value {
+		return nil
+	}
+	//负数过期时间表示立即过期
+	item.Expiration = -1
+	err = mem.conn.CompareAndSwap(item)
+	if err == memcache.ErrCacheMiss || err == memcache.ErrCASConflict || err == memcache.ErrNotStored {
+		return nil
+	}
+	return err
+}
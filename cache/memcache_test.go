@@ -25,4 +25,14 @@ func TestMemcache(t *testing.T) {
 	if err = mem.Delete("username"); err != nil {
 		t.Errorf("delete Error , err=%v", err)
 	}
+
+	if ok, err := mem.Acquire("lock", "owner", timeoutDuration); err != nil || !ok {
+		t.Errorf("Acquire Error , ok=%v err=%v", ok, err)
+	}
+	if err = mem.Release("lock", "other"); err != nil || !mem.IsExist("lock") {
+		t.Errorf("Release by other owner should keep lock, err=%v", err)
+	}
+	if err = mem.Release("lock", "owner"); err != nil || mem.IsExist("lock") {
+		t.Errorf("Release Error , err=%v", err)
+	}
 }
@@ -26,9 +26,12 @@ func NewMemory() *Memory {
 
 //Get return cached value
 func (mem *Memory) Get(key string) interface{} {
+	mem.Lock()
+	defer mem.Unlock()
+
 	if ret, ok := mem.data[key]; ok {
 		if ret.Expired.Before(time.Now()) {
-			mem.deleteKey(key)
+			delete(mem.data, key)
 			return nil
 		}
 		return ret.Data
@@ -37,9 +40,12 @@ func (mem *Memory) Get(key string) interface{} {
 }
 
 func (mem *Memory) GetString(key string) string {
+	mem.Lock()
+	defer mem.Unlock()
+
 	if ret, ok := mem.data[key]; ok {
 		if ret.Expired.Before(time.Now()) {
-			mem.deleteKey(key)
+			delete(mem.data, key)
 			return ""
 		}
 		return ret.Data.(string)
@@ -49,9 +55,12 @@ func (mem *Memory) GetString(key string) string {
 
 // IsExist check value exists in memcache.
 func (mem *Memory) IsExist(key string) bool {
+	mem.Lock()
+	defer mem.Unlock()
+
 	if ret, ok := mem.data[key]; ok {
 		if ret.Expired.Before(time.Now()) {
-			mem.deleteKey(key)
+			delete(mem.data, key)
 			return false
 		}
 		return true
@@ -94,3 +103,29 @@ func (mem *Memory) deleteKey(key string) error {
 	delete(mem.data, key)
 	return nil
 }
+
+//Acquire 进程内的锁，用于单实例部署或测试
+func (mem *Memory) Acquire(key string, value string, ttl time.Duration) (bool, error) {
+	mem.Lock()
+	defer mem.Unlock()
+
+	if ret, ok := mem.data[key]; ok && ret.Expired.After(time.Now()) {
+		return false, nil
+	}
+	mem.data[key] = &data{
+		Data:    value,
+		Expired: time.Now().Add(ttl),
+	}
+	return true, nil
+}
+
+//Release 释放锁
+func (mem *Memory) Release(key string, value string) error {
+	mem.Lock()
+	defer mem.Unlock()
+
+	if ret, ok := mem.data[key]; ok && ret.Data == value {
+		delete(mem.data, key)
+	}
+	return nil
+}
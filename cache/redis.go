@@ -129,3 +129,30 @@ func (r *Redis) Delete(key string) error {
 
 	return nil
 }
+
+//unlockScript 仅当锁仍由当前持有者持有时删除
+var unlockScript = redis.NewScript(1, `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`)
+
+//Acquire 使用 SET key value NX PX ttl 获取锁
+func (r *Redis) Acquire(key string, value string, ttl time.Duration) (bool, error) {
+	conn := r.conn.Get()
+	defer conn.Close()
+
+	_, err := redis.String(conn.Do("SET", key, value, "NX", "PX", int64(ttl/time.Millisecond)))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+//Release 释放锁
+func (r *Redis) Release(key string, value string) error {
+	conn := r.conn.Get()
+	defer conn.Close()
+
+	_, err := unlockScript.Do(conn, key, value)
+	return err
+}
@@ -0,0 +1,191 @@
+//wechat-sim 模拟微信服务器向本地运行的消息接口推送消息和事件，按照微信的方式签名及加密，并打印解密后的回复
+//
+//用法：
+//	wechat-sim -url http://localhost:8080/wechat -token TOKEN [-appid APPID -aeskey KEY] [-json] <类型> [参数]
+//
+//类型：
+//	text <内容>               文本消息
+//	click <EventKey>          点击菜单拉取消息
+//	view <URL>                点击菜单跳转链接
+//	subscribe [场景值]         关注，带场景值时模拟扫描带参数二维码关注
+//	unsubscribe               取消关注
+//	scan <场景值>              已关注用户扫描带参数二维码
+//	templatejobfinish [状态]   模板消息发送结果，默认success
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dcsunny/wechat/message"
+	"github.com/dcsunny/wechat/wechattest"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+//run 解析参数并推送一条消息，返回进程退出码：0成功，1请求失败，2参数错误
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("wechat-sim", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	target := flags.String("url", "http://localhost:8080/", "消息接口地址")
+	token := flags.String("token", "", "Token（必填）")
+	appID := flags.String("appid", "", "AppID，安全模式下加密使用")
+	aesKey := flags.String("aeskey", "", "EncodingAESKey，设置后使用安全模式，需同时设置-appid")
+	from := flags.String("from", "oWECHATSIMOPENID", "发送者openid")
+	to := flags.String("to", wechattest.DefaultToUserName, "公众号原始ID")
+	useJSON := flags.Bool("json", false, "使用JSON格式推送(小程序)")
+	timeout := flags.Duration("timeout", 10*time.Second, "请求超时时间")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: wechat-sim -token TOKEN [flags] text|click|view|subscribe|unsubscribe|scan|templatejobfinish [args]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	usageError := func(err error) int {
+		fmt.Fprintln(stderr, err)
+		flags.Usage()
+		return 2
+	}
+	switch {
+	case *target == "":
+		return usageError(fmt.Errorf("缺少-url"))
+	case *token == "":
+		return usageError(fmt.Errorf("缺少-token"))
+	case *aesKey != "" && *appID == "":
+		return usageError(fmt.Errorf("设置-aeskey时需要-appid"))
+	}
+	msg, err := buildMessage(*from, *to, flags.Args())
+	if err != nil {
+		return usageError(err)
+	}
+
+	callback := &wechattest.Callback{
+		Token:          *token,
+		AppID:          *appID,
+		EncodingAESKey: *aesKey,
+		ToUserName:     *to,
+		JSON:           *useJSON,
+		Client:         &http.Client{Timeout: *timeout},
+	}
+	reply, err := callback.Post(*target, msg)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	printReply(stdout, reply, *useJSON)
+	return 0
+}
+
+//templateSendJobFinishEvent 模板消息发送结果事件，微信推送的消息ID字段为MsgID而不是普通消息的MsgId
+type templateSendJobFinishEvent struct {
+	message.CommonToken
+	Event  message.EventType `xml:"Event" json:"Event"`
+	MsgID  int64             `xml:"MsgID" json:"MsgID"`
+	Status string            `xml:"Status" json:"Status"`
+}
+
+func buildMessage(openID, toUserName string, args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("缺少消息类型")
+	}
+	arg := strings.Join(args[1:], " ")
+	switch args[0] {
+	case "text":
+		if arg == "" {
+			return nil, fmt.Errorf("text需要消息内容")
+		}
+		return wechattest.TextMessage(openID, arg), nil
+	case "click":
+		return wechattest.EventMessage(openID, message.EventClick, arg), nil
+	case "view":
+		return wechattest.EventMessage(openID, message.EventView, arg), nil
+	case "subscribe":
+		msg := wechattest.EventMessage(openID, message.EventSubscribe, "")
+		if arg != "" {
+			msg.EventKey = "qrscene_" + arg
+			msg.Ticket = "wechat-sim-ticket"
+		}
+		return msg, nil
+	case "unsubscribe":
+		return wechattest.EventMessage(openID, message.EventUnsubscribe, ""), nil
+	case "scan":
+		if arg == "" {
+			return nil, fmt.Errorf("scan需要场景值")
+		}
+		msg := wechattest.EventMessage(openID, message.EventScan, arg)
+		msg.Ticket = "wechat-sim-ticket"
+		return msg, nil
+	case "templatejobfinish":
+		msg := templateSendJobFinishEvent{
+			CommonToken: wechattest.EventMessage(openID, message.EventTemplateSendJobFinish, "").CommonToken,
+			Event:       message.EventTemplateSendJobFinish,
+			MsgID:       time.Now().UnixNano() / int64(time.Millisecond),
+			Status:      "success",
+		}
+		msg.ToUserName = message.CDATA(toUserName)
+		if arg != "" {
+			msg.Status = arg
+		}
+		return msg, nil
+	}
+	return nil, fmt.Errorf("不支持的消息类型: %s", args[0])
+}
+
+func printReply(w io.Writer, reply *wechattest.CallbackReply, useJSON bool) {
+	fmt.Fprintf(w, "HTTP %d", reply.StatusCode)
+	if reply.MsgType != "" {
+		fmt.Fprintf(w, " MsgType=%s", reply.MsgType)
+	}
+	fmt.Fprintln(w)
+	body := bytes.TrimSpace(reply.Body)
+	if len(body) == 0 {
+		fmt.Fprintln(w, "(empty reply)")
+		return
+	}
+	var out bytes.Buffer
+	var err error
+	if useJSON {
+		err = json.Indent(&out, body, "", "  ")
+	} else {
+		err = indentXML(&out, body)
+	}
+	if err != nil {
+		out.Reset()
+		out.Write(body)
+	}
+	fmt.Fprintln(w, out.String())
+}
+
+//indentXML 重新缩进XML便于阅读，CDATA中的内容会转义后输出
+func indentXML(w io.Writer, data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return encoder.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		if charData, ok := token.(xml.CharData); ok && len(bytes.TrimSpace(charData)) == 0 {
+			continue
+		}
+		if err := encoder.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+}
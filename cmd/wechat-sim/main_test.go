@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dcsunny/wechat"
+	"github.com/dcsunny/wechat/message"
+)
+
+func TestRun(t *testing.T) {
+	const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	wc := wechat.NewWechat(&wechat.Config{AppID: "appid", Token: "token", EncodingAESKey: aesKey})
+	srv := wc.NewServer()
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		if msg.MsgType == message.MsgTypeEvent {
+			return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("event " + string(msg.Event))}
+		}
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("echo " + msg.Content)}
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	for _, c := range []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"-url", ts.URL, "-token", "token", "text", "hello", "world"}, "echo hello world"},
+		{[]string{"-url", ts.URL, "-token", "token", "-appid", "appid", "-aeskey", aesKey, "subscribe"}, "event subscribe"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(c.args, &stdout, &stderr); code != 0 {
+			t.Fatalf("%v: exit %d, stderr %s", c.args, code, stderr.String())
+		}
+		if out := stdout.String(); !strings.Contains(out, "HTTP 200 MsgType=text") || !strings.Contains(out, c.expect) {
+			t.Errorf("%v: unexpected output %s", c.args, out)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-url", ts.URL, "-token", "wrong", "text", "hi"}, &stdout, &stderr); code != 1 {
+		t.Errorf("expect exit 1 for invalid signature, got %d", code)
+	}
+}
+
+func TestRunTemplateJobFinish(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		w.Write([]byte("success"))
+	}))
+	defer ts.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-url", ts.URL, "-token", "token", "-to", "gh_sim", "templatejobfinish", "failed:user block"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d, stderr %s", code, stderr.String())
+	}
+	for _, expect := range []string{"<MsgID>", "<Event>TEMPLATESENDJOBFINISH</Event>", "<Status>failed:user block</Status>", "<ToUserName><![CDATA[gh_sim]]></ToUserName>"} {
+		if !strings.Contains(body, expect) {
+			t.Errorf("expect %s in %s", expect, body)
+		}
+	}
+	if strings.Contains(body, "<MsgId>") {
+		t.Errorf("unexpected MsgId in %s", body)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, c := range []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"text", "hi"}, "缺少-token"},
+		{[]string{"-url", "", "-token", "token", "text", "hi"}, "缺少-url"},
+		{[]string{"-token", "token", "-aeskey", "key", "text", "hi"}, "需要-appid"},
+		{[]string{"-token", "token"}, "缺少消息类型"},
+		{[]string{"-token", "token", "image"}, "不支持的消息类型"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(c.args, &stdout, &stderr); code != 2 {
+			t.Errorf("%v: expect exit 2, got %d", c.args, code)
+		}
+		if out := stderr.String(); !strings.Contains(out, c.expect) || !strings.Contains(out, "usage: wechat-sim") {
+			t.Errorf("%v: unexpected stderr %s", c.args, out)
+		}
+	}
+}
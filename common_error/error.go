@@ -43,18 +43,15 @@ func DecodeWithError(response []byte, obj interface{}, apiName string) error {
 		return fmt.Errorf("errcode or errmsg is invalid")
 	}
 	if errCode.Int() != 0 {
-		return fmt.Errorf("%s Error , errcode=%d , errmsg=%s", apiName, errCode.Int(), errMsg.String())
+		return define.NewError(apiName, errCode.Int(), errMsg.String())
 	}
 	return nil
 }
 
+//CommonErrorHandle 将CommonError转换为*define.Error，access_token失效的处理及重试已在context的请求方法中完成
 func CommonErrorHandle(commError define.CommonError, context *context.Context, apiName string) error {
 	if commError.ErrCode == 0 {
 		return nil
 	}
-	if commError.ErrCode == 40001 {
-		accessTokenCacheKey := fmt.Sprintf(define.AccessTokenCacheKey, context.AppID)
-		context.Cache.Delete(accessTokenCacheKey)
-	}
-	return fmt.Errorf("%s Error , errcode=%d , errmsg=%s", apiName, commError.ErrCode, commError.ErrMsg)
+	return define.NewError(apiName, commError.ErrCode, commError.ErrMsg)
 }
@@ -1,8 +1,10 @@
 package context
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
+	neturl "net/url"
 	"sync"
 	"time"
 
@@ -13,6 +15,8 @@ import (
 const (
 	//AccessTokenURL 获取access_token的接口
 	AccessTokenURL = "https://api.weixin.qq.com/cgi-bin/token"
+	//StableAccessTokenURL 获取稳定版access_token的接口
+	StableAccessTokenURL = "https://api.weixin.qq.com/cgi-bin/stable_token"
 )
 
 //ResAccessToken struct
@@ -36,10 +40,18 @@ func (ctx *Context) SetGetAccessTokenFunc(f GetAccessTokenFunc) {
 	ctx.accessTokenFunc = f
 }
 
+//GetAccessTokenWithContext 获取access_token，从微信服务器获取时受c的取消和超时控制
+func (ctx *Context) GetAccessTokenWithContext(c stdcontext.Context) (accessToken string, err error) {
+	return ctx.WithContext(c).GetAccessToken()
+}
+
 //GetAccessToken 获取access_token
 func (ctx *Context) GetAccessToken() (accessToken string, err error) {
 	ctx.accessTokenLock.Lock()
 	defer ctx.accessTokenLock.Unlock()
+	if ctx.component != nil {
+		return ctx.boundComponent().GetAuthrAccessToken(ctx.AppID)
+	}
 	if ctx.accessTokenFunc != nil {
 		return ctx.accessTokenFunc(ctx)
 	}
@@ -49,25 +61,91 @@ func (ctx *Context) GetAccessToken() (accessToken string, err error) {
 		return
 	}
 	//从微信服务器获取
+	return ctx.refreshAccessToken("")
+}
+
+//RefreshAccessToken 在缓存过期前主动刷新access_token，用于后台刷新。与GetAccessToken使用相同的锁，
+//多实例同时刷新时只有一个实例请求微信，其他实例返回的ExpiresIn为0；
+//通过AuthorizerContext创建时使用refresh_token刷新授权方的token，设置了GetAccessTokenFunc时调用该方法
+func (ctx *Context) RefreshAccessToken() (ResAccessToken, error) {
+	ctx.accessTokenLock.Lock()
+	defer ctx.accessTokenLock.Unlock()
+	if ctx.component != nil {
+		token, err := ctx.boundComponent().refreshAuthrTokenFromStore(ctx.AppID)
+		if err != nil {
+			return ResAccessToken{}, err
+		}
+		return ResAccessToken{AccessToken: token.AccessToken, ExpiresIn: token.ExpiresIn}, nil
+	}
+	if ctx.accessTokenFunc != nil {
+		accessToken, err := ctx.accessTokenFunc(ctx)
+		return ResAccessToken{AccessToken: accessToken}, err
+	}
+	accessTokenCacheKey := fmt.Sprintf(define.AccessTokenCacheKey, ctx.AppID)
+	return ctx.fetchAccessTokenWithLock(ctx.Cache.GetString(accessTokenCacheKey), "")
+}
+
+//refreshAccessToken 从微信服务器获取access_token，配置了Locker时同一时间只有一个实例获取，
+//其他实例等待缓存中出现不同于staleToken的token
+func (ctx *Context) refreshAccessToken(staleToken string) (string, error) {
+	//staleToken已失效时，稳定版access_token需要强制刷新才能获取新的token
+	resAccessToken, err := ctx.fetchAccessTokenWithLock(staleToken, staleToken)
+	return resAccessToken.AccessToken, err
+}
+
+//fetchAccessTokenWithLock 在锁的保护下从微信服务器获取access_token，缓存中出现不同于staleToken的token时视为已被其他实例刷新，
+//此时返回的ExpiresIn为0；invalidToken不为空时强制刷新
+func (ctx *Context) fetchAccessTokenWithLock(staleToken, invalidToken string) (ResAccessToken, error) {
+	accessTokenCacheKey := fmt.Sprintf(define.AccessTokenCacheKey, ctx.AppID)
 	var resAccessToken ResAccessToken
-	resAccessToken, err = ctx.GetAccessTokenFromServer()
+	accessToken, err := ctx.RefreshWithLock(fmt.Sprintf(define.AccessTokenLockKey, ctx.AppID), func() string {
+		if accessToken := ctx.Cache.GetString(accessTokenCacheKey); accessToken != staleToken {
+			return accessToken
+		}
+		return ""
+	}, func() (string, error) {
+		var err error
+		resAccessToken, err = ctx.getAccessTokenFromServer(invalidToken)
+		return resAccessToken.AccessToken, err
+	})
 	if err != nil {
-		return
+		return ResAccessToken{}, err
 	}
-
-	accessToken = resAccessToken.AccessToken
-	return
+	if resAccessToken.AccessToken != accessToken {
+		resAccessToken = ResAccessToken{AccessToken: accessToken}
+	}
+	return resAccessToken, nil
 }
 
 //GetAccessTokenFromServer 强制从微信服务器获取token
 func (ctx *Context) GetAccessTokenFromServer() (resAccessToken ResAccessToken, err error) {
-	accessTokenUrl := AccessTokenURL
-	if ctx.AccessTokenURL != "" {
-		accessTokenUrl = ctx.AccessTokenURL
-	}
-	url := fmt.Sprintf("%s?grant_type=client_credential&appid=%s&secret=%s", accessTokenUrl, ctx.AppID, ctx.AppSecret)
+	return ctx.getAccessTokenFromServer("")
+}
+
+//getAccessTokenFromServer 从微信服务器获取token，invalidToken为已失效的token，不为空时：
+//StableAccessToken模式下强制刷新，设置了AccessTokenURL时通过invalid_token参数通知中控服务刷新。
+//设置了AccessTokenURL时总是从该地址获取，StableAccessToken只对直接请求微信生效，是否使用稳定版接口由中控服务决定
+func (ctx *Context) getAccessTokenFromServer(invalidToken string) (resAccessToken ResAccessToken, err error) {
 	var body []byte
-	body, err = util.HTTPGet(url)
+	if ctx.StableAccessToken && ctx.AccessTokenURL == "" {
+		req := map[string]interface{}{
+			"grant_type":    "client_credential",
+			"appid":         ctx.AppID,
+			"secret":        ctx.AppSecret,
+			"force_refresh": invalidToken != "",
+		}
+		body, err = ctx.PostJSON(StableAccessTokenURL, req)
+	} else {
+		accessTokenUrl := AccessTokenURL
+		if ctx.AccessTokenURL != "" {
+			accessTokenUrl = ctx.AccessTokenURL
+		}
+		url := fmt.Sprintf("%s%sgrant_type=client_credential&appid=%s&secret=%s", accessTokenUrl, util.QuerySeparator(accessTokenUrl), ctx.AppID, ctx.AppSecret)
+		if ctx.AccessTokenURL != "" && invalidToken != "" {
+			url += "&invalid_token=" + neturl.QueryEscape(invalidToken)
+		}
+		body, err = ctx.HTTPGet(url)
+	}
 	if err != nil {
 		return
 	}
@@ -76,12 +154,69 @@ func (ctx *Context) GetAccessTokenFromServer() (resAccessToken ResAccessToken, e
 		return
 	}
 	if resAccessToken.ErrMsg != "" {
-		err = fmt.Errorf("get access_token error : errcode=%v , errormsg=%v", resAccessToken.ErrCode, resAccessToken.ErrMsg)
+		err = define.NewError("GetAccessToken", resAccessToken.ErrCode, resAccessToken.ErrMsg)
 		return
 	}
 
 	accessTokenCacheKey := fmt.Sprintf(define.AccessTokenCacheKey, ctx.AppID)
-	expires := resAccessToken.ExpiresIn - 1500
-	err = ctx.Cache.SetString(accessTokenCacheKey, resAccessToken.AccessToken, time.Duration(expires)*time.Second)
+	err = ctx.Cache.SetString(accessTokenCacheKey, resAccessToken.AccessToken, CacheExpires(resAccessToken.ExpiresIn))
+	return
+}
+
+//CacheExpires 根据微信返回的有效期(秒)计算缓存时间，一般提前1500秒过期；
+//稳定版access_token返回的是剩余有效期，不足时缓存其一半的时间
+func CacheExpires(expiresIn int64) time.Duration {
+	expires := expiresIn - 1500
+	if expires < expiresIn/2 {
+		expires = expiresIn / 2
+	}
+	return time.Duration(expires) * time.Second
+}
+
+//RefreshInvalidAccessToken 在调用接口返回access_token无效时使用：
+//若invalidToken为当前缓存的token则将其删除并从微信服务器重新获取，若已被其他请求刷新则直接返回新的token，
+//invalidToken不是由当前AppID签发时返回ok=false
+func (ctx *Context) RefreshInvalidAccessToken(invalidToken string) (accessToken string, ok bool, err error) {
+	resAccessToken, ok, err := ctx.RefreshInvalidAccessTokenWithExpiresIn(invalidToken)
+	return resAccessToken.AccessToken, ok, err
+}
+
+//RefreshInvalidAccessTokenWithExpiresIn 与RefreshInvalidAccessToken相同，同时返回微信返回的有效期(秒)，
+//token已被其他请求或实例刷新、无法得知有效期时ExpiresIn为0
+func (ctx *Context) RefreshInvalidAccessTokenWithExpiresIn(invalidToken string) (resAccessToken ResAccessToken, ok bool, err error) {
+	if ctx.Cache == nil || ctx.accessTokenFunc != nil || invalidToken == "" {
+		return
+	}
+	ctx.accessTokenLock.Lock()
+	defer ctx.accessTokenLock.Unlock()
+	if ctx.component != nil {
+		resAccessToken.AccessToken, ok, err = ctx.boundComponent().refreshInvalidAuthrToken(ctx.AppID, invalidToken)
+		return
+	}
+
+	accessTokenCacheKey := fmt.Sprintf(define.AccessTokenCacheKey, ctx.AppID)
+	invalidAccessTokenCacheKey := fmt.Sprintf(define.InvalidAccessTokenCacheKey, ctx.AppID)
+	cachedToken := ctx.Cache.GetString(accessTokenCacheKey)
+	switch {
+	case cachedToken == invalidToken:
+		//缓存中的token已失效，删除并记录后重新获取
+		if err = ctx.Cache.Delete(accessTokenCacheKey); err != nil {
+			return
+		}
+		if err = ctx.Cache.SetString(invalidAccessTokenCacheKey, invalidToken, 10*time.Minute); err != nil {
+			return
+		}
+	case ctx.Cache.GetString(invalidAccessTokenCacheKey) == invalidToken:
+		//已被其他请求判定为失效，新token已写入缓存时直接使用
+		if cachedToken != "" {
+			return ResAccessToken{AccessToken: cachedToken}, true, nil
+		}
+	default:
+		return
+	}
+
+	ok = true
+	//invalidToken已失效，稳定版access_token需要强制刷新才能获取新的token
+	resAccessToken, err = ctx.fetchAccessTokenWithLock(invalidToken, invalidToken)
 	return
 }
@@ -1,8 +1,17 @@
 package context
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/define"
 )
 
 func TestContext_SetCustomAccessTokenFunc(t *testing.T) {
@@ -28,3 +37,147 @@ func TestContext_NoSetCustomAccessTokenFunc(t *testing.T) {
 		t.Error("error accessTokenFunc")
 	}
 }
+
+func TestContext_RetryOnInvalidAccessToken(t *testing.T) {
+	var tokenRequests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/token":
+			tokenRequests++
+			w.Write([]byte(`{"access_token":"new_token","expires_in":7200}`))
+		default:
+			if r.URL.Query().Get("access_token") != "new_token" {
+				w.Write([]byte(`{"errcode":40001,"errmsg":"invalid credential"}`))
+				return
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	}))
+	defer ts.Close()
+
+	ctx := &Context{
+		AppID:           "appid",
+		APIHost:         ts.URL,
+		Cache:           cache.NewMemory(),
+		accessTokenLock: new(sync.RWMutex),
+	}
+	ctx.Cache.SetString(fmt.Sprintf(define.AccessTokenCacheKey, ctx.AppID), "old_token", time.Hour)
+
+	res, err := ctx.HTTPGet("https://api.weixin.qq.com/cgi-bin/menu/get?access_token=old_token")
+	if err != nil || string(res) != `{"errcode":0,"errmsg":"ok"}` {
+		t.Fatalf("expect retried success, got %s %v", res, err)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("expect 1 token request, got %d", tokenRequests)
+	}
+	if accessToken, _ := ctx.GetAccessToken(); accessToken != "new_token" {
+		t.Errorf("expect new_token cached, got %s", accessToken)
+	}
+
+	//不是当前AppID签发的token（如网页授权的access_token）不重试
+	res, _ = ctx.HTTPGet("https://api.weixin.qq.com/sns/userinfo?access_token=user_token")
+	if string(res) != `{"errcode":40001,"errmsg":"invalid credential"}` || tokenRequests != 1 {
+		t.Errorf("expect no retry for foreign token, got %s", res)
+	}
+}
+
+func TestContext_RefreshWithLock(t *testing.T) {
+	var tokenRequests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenRequests, 1)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"access_token":"new_token","expires_in":7200}`))
+	}))
+	defer ts.Close()
+
+	//模拟多个实例：各自的进程内锁，共用缓存和分布式锁
+	memory := cache.NewMemory()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		ctx := &Context{
+			AppID:           "appid",
+			APIHost:         ts.URL,
+			Cache:           memory,
+			Locker:          memory,
+			accessTokenLock: new(sync.RWMutex),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if accessToken, err := ctx.GetAccessToken(); accessToken != "new_token" || err != nil {
+				t.Errorf("expect new_token, got %s %v", accessToken, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if tokenRequests != 1 {
+		t.Errorf("expect 1 token request, got %d", tokenRequests)
+	}
+}
+
+func TestContext_StableAccessToken(t *testing.T) {
+	var forceRefresh []bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/stable_token":
+			var req struct {
+				ForceRefresh bool `json:"force_refresh"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			forceRefresh = append(forceRefresh, req.ForceRefresh)
+			w.Write([]byte(fmt.Sprintf(`{"access_token":"token_%d","expires_in":7200}`, len(forceRefresh))))
+		default:
+			if r.URL.Query().Get("access_token") == "token_1" {
+				w.Write([]byte(`{"errcode":40001,"errmsg":"invalid credential"}`))
+				return
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	}))
+	defer ts.Close()
+
+	ctx := &Context{
+		AppID:             "appid",
+		APIHost:           ts.URL,
+		StableAccessToken: true,
+		Cache:             cache.NewMemory(),
+		accessTokenLock:   new(sync.RWMutex),
+	}
+	accessToken, err := ctx.GetAccessToken()
+	if accessToken != "token_1" || err != nil {
+		t.Fatalf("expect token_1, got %s %v", accessToken, err)
+	}
+	if _, err := ctx.HTTPGet("https://api.weixin.qq.com/cgi-bin/menu/get?access_token=" + accessToken); err != nil {
+		t.Fatal(err)
+	}
+	if len(forceRefresh) != 2 || forceRefresh[0] || !forceRefresh[1] {
+		t.Errorf("expect force_refresh only on invalid token, got %v", forceRefresh)
+	}
+}
+
+func TestContext_StableAccessTokenWithAccessTokenURL(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.URL.Query().Get("invalid_token"))
+		w.Write([]byte(fmt.Sprintf(`{"access_token":"token_%d","expires_in":7200}`, len(requests))))
+	}))
+	defer ts.Close()
+
+	ctx := &Context{
+		AppID:             "appid",
+		APIHost:           ts.URL,
+		AccessTokenURL:    ts.URL + "/relay/token",
+		StableAccessToken: true,
+		Cache:             cache.NewMemory(),
+		accessTokenLock:   new(sync.RWMutex),
+	}
+	if accessToken, err := ctx.GetAccessToken(); accessToken != "token_1" || err != nil {
+		t.Fatalf("expect token_1, got %s %v", accessToken, err)
+	}
+	if accessToken, ok, err := ctx.RefreshInvalidAccessToken("token_1"); !ok || accessToken != "token_2" || err != nil {
+		t.Fatalf("expect token_2, got %s %v %v", accessToken, ok, err)
+	}
+	if len(requests) != 2 || requests[0] != "GET /relay/token " || requests[1] != "GET /relay/token token_1" {
+		t.Errorf("expect AccessTokenURL used in stable mode, got %q", requests)
+	}
+}
@@ -3,10 +3,10 @@ package context
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -16,6 +16,9 @@ const (
 	refreshTokenURL         = "https://api.weixin.qq.com/cgi-bin/component/api_authorizer_token?component_access_token=%s"
 	getComponentInfoURL     = "https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_info?component_access_token=%s"
 	getComponentConfigURL   = "https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_option?component_access_token=%s"
+	setComponentConfigURL   = "https://api.weixin.qq.com/cgi-bin/component/api_set_authorizer_option?component_access_token=%s"
+	getAuthorizerListURL    = "https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_list?component_access_token=%s"
+	componentClearQuotaURL  = "https://api.weixin.qq.com/cgi-bin/component/clear_quota?component_access_token=%s"
 )
 
 // ComponentAccessToken 第三方平台
@@ -24,36 +27,81 @@ type ComponentAccessToken struct {
 	ExpiresIn   int64  `json:"expires_in"`
 }
 
-// GetComponentAccessToken 获取 ComponentAccessToken
+// GetComponentAccessToken 获取 ComponentAccessToken，缓存中没有时使用保存的component_verify_ticket获取
 func (ctx *Context) GetComponentAccessToken() (string, error) {
 	accessTokenCacheKey := fmt.Sprintf(define.ComponentAccessTokenCacheKey, ctx.AppID)
-	accessToken := ctx.Cache.GetString(accessTokenCacheKey)
-	if accessToken != "" {
-		return "", fmt.Errorf("cannot get authorizer %s access token", ctx.AppID)
+	if accessToken := ctx.Cache.GetString(accessTokenCacheKey); accessToken != "" {
+		return accessToken, nil
 	}
-	return accessToken, nil
+	verifyTicket, err := ctx.GetComponentVerifyTicket()
+	if err != nil {
+		return "", err
+	}
+	at, err := ctx.SetComponentAccessToken(verifyTicket)
+	if err != nil {
+		return "", err
+	}
+	return at.AccessToken, nil
+}
+
+// GetComponentVerifyTicket 返回通过SetComponentVerifyTicket保存的component_verify_ticket，尚未收到推送时返回错误
+func (ctx *Context) GetComponentVerifyTicket() (string, error) {
+	verifyTicket, err := ctx.componentStore().GetVerifyTicket(ctx.AppID)
+	if err != nil {
+		return "", err
+	}
+	if verifyTicket == "" {
+		return "", fmt.Errorf("cannot get component %s access token: component_verify_ticket not received", ctx.AppID)
+	}
+	return verifyTicket, nil
+}
+
+// SetComponentVerifyTicket 保存微信推送的component_verify_ticket，之后获取component_access_token时使用
+func (ctx *Context) SetComponentVerifyTicket(verifyTicket string) error {
+	return ctx.componentStore().SetVerifyTicket(ctx.AppID, verifyTicket)
 }
 
 // SetComponentAccessToken 通过component_verify_ticket 获取 ComponentAccessToken
 func (ctx *Context) SetComponentAccessToken(verifyTicket string) (*ComponentAccessToken, error) {
-	body := map[string]string{
-		"component_appid":         ctx.AppID,
-		"component_appsecret":     ctx.AppSecret,
-		"component_verify_ticket": verifyTicket,
-	}
-	respBody, err := util.PostJSON(componentAccessTokenURL, body)
+	accessTokenCacheKey := fmt.Sprintf(define.ComponentAccessTokenCacheKey, ctx.AppID)
+	staleToken := ctx.Cache.GetString(accessTokenCacheKey)
+	var at *ComponentAccessToken
+	//多实例同时收到推送时只由一个实例刷新，其他实例直接使用其结果(此时ExpiresIn为0)
+	accessToken, err := ctx.RefreshWithLock(fmt.Sprintf(define.ComponentAccessTokenLockKey, ctx.AppID), func() string {
+		if accessToken := ctx.Cache.GetString(accessTokenCacheKey); accessToken != staleToken {
+			return accessToken
+		}
+		return ""
+	}, func() (string, error) {
+		body := map[string]string{
+			"component_appid":         ctx.AppID,
+			"component_appsecret":     ctx.AppSecret,
+			"component_verify_ticket": verifyTicket,
+		}
+		respBody, err := ctx.PostJSON(componentAccessTokenURL, body)
+		if err != nil {
+			return "", err
+		}
+		if err := define.DecodeWithCommonError(respBody, "GetComponentAccessToken"); err != nil {
+			return "", err
+		}
+
+		at = &ComponentAccessToken{}
+		if err := json.Unmarshal(respBody, at); err != nil {
+			return "", err
+		}
+
+		if err := ctx.Cache.SetString(accessTokenCacheKey, at.AccessToken, CacheExpires(at.ExpiresIn)); err != nil {
+			return "", err
+		}
+		return at.AccessToken, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	at := &ComponentAccessToken{}
-	if err := json.Unmarshal(respBody, at); err != nil {
-		return nil, err
+	if at == nil {
+		at = &ComponentAccessToken{AccessToken: accessToken}
 	}
-
-	accessTokenCacheKey := fmt.Sprintf(define.ComponentAccessTokenCacheKey, ctx.AppID)
-	expires := at.ExpiresIn - 1500
-	ctx.Cache.SetString(accessTokenCacheKey, at.AccessToken, time.Duration(expires)*time.Second)
 	return at, nil
 }
 
@@ -67,10 +115,13 @@ func (ctx *Context) GetPreCode() (string, error) {
 		"component_appid": ctx.AppID,
 	}
 	uri := fmt.Sprintf(getPreCodeURL, cat)
-	body, err := util.PostJSON(uri, req)
+	body, err := ctx.PostJSON(uri, req)
 	if err != nil {
 		return "", err
 	}
+	if err := define.DecodeWithCommonError(body, "GetPreCode"); err != nil {
+		return "", err
+	}
 
 	var ret struct {
 		PreCode string `json:"pre_auth_code"`
@@ -106,7 +157,7 @@ type AuthrAccessToken struct {
 	RefreshToken string `json:"authorizer_refresh_token"`
 }
 
-// QueryAuthCode 使用授权码换取公众号或小程序的接口调用凭据和授权信息
+// QueryAuthCode 使用授权码换取公众号或小程序的接口调用凭据和授权信息，并保存授权方的access_token及refresh_token
 func (ctx *Context) QueryAuthCode(authCode string) (*AuthBaseInfo, error) {
 	cat, err := ctx.GetComponentAccessToken()
 	if err != nil {
@@ -118,10 +169,13 @@ func (ctx *Context) QueryAuthCode(authCode string) (*AuthBaseInfo, error) {
 		"authorization_code": authCode,
 	}
 	uri := fmt.Sprintf(queryAuthURL, cat)
-	body, err := util.PostJSON(uri, req)
+	body, err := ctx.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
+	if err := define.DecodeWithCommonError(body, "QueryAuthCode"); err != nil {
+		return nil, err
+	}
 
 	var ret struct {
 		Info *AuthBaseInfo `json:"authorization_info"`
@@ -130,6 +184,12 @@ func (ctx *Context) QueryAuthCode(authCode string) (*AuthBaseInfo, error) {
 	if err := json.Unmarshal(body, &ret); err != nil {
 		return nil, err
 	}
+	if ret.Info == nil {
+		return nil, fmt.Errorf("QueryAuthCode: authorization_info is empty")
+	}
+	if err := ctx.saveAuthrToken(&ret.Info.AuthrAccessToken); err != nil {
+		return nil, err
+	}
 
 	return ret.Info, nil
 }
@@ -141,36 +201,184 @@ func (ctx *Context) RefreshAuthrToken(appid, refreshToken string) (*AuthrAccessT
 		return nil, err
 	}
 
-	req := map[string]string{
-		"component_appid":          ctx.AppID,
-		"authorizer_appid":         appid,
-		"authorizer_refresh_token": refreshToken,
-	}
-	uri := fmt.Sprintf(refreshTokenURL, cat)
-	body, err := util.PostJSON(uri, req)
+	authrTokenKey := ctx.authrTokenCacheKey(appid)
+	staleToken := ctx.Cache.GetString(authrTokenKey)
+	var ret *AuthrAccessToken
+	accessToken, err := ctx.RefreshWithLock(fmt.Sprintf(define.AuthorizerAccessTokenLockKey, ctx.AppID, appid), func() string {
+		if accessToken := ctx.Cache.GetString(authrTokenKey); accessToken != staleToken {
+			return accessToken
+		}
+		return ""
+	}, func() (string, error) {
+		req := map[string]string{
+			"component_appid":          ctx.AppID,
+			"authorizer_appid":         appid,
+			"authorizer_refresh_token": refreshToken,
+		}
+		uri := fmt.Sprintf(refreshTokenURL, cat)
+		body, err := ctx.PostJSON(uri, req)
+		if err != nil {
+			return "", err
+		}
+		if err := define.DecodeWithCommonError(body, "RefreshAuthrToken"); err != nil {
+			return "", err
+		}
+
+		ret = &AuthrAccessToken{Appid: appid, RefreshToken: refreshToken}
+		if err := json.Unmarshal(body, ret); err != nil {
+			return "", err
+		}
+		if err := ctx.saveAuthrToken(ret); err != nil {
+			return "", err
+		}
+		return ret.AccessToken, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	if ret == nil {
+		ret = &AuthrAccessToken{Appid: appid, AccessToken: accessToken, RefreshToken: refreshToken}
+	}
+	return ret, nil
+}
+
+//authrTokenCacheKey 授权方authorizer_access_token的缓存key
+func (ctx *Context) authrTokenCacheKey(appid string) string {
+	return fmt.Sprintf(define.AuthorizerAccessTokenCacheKey, ctx.AppID, appid)
+}
+
+//saveAuthrToken 缓存授权方的access_token并保存refresh_token
+func (ctx *Context) saveAuthrToken(token *AuthrAccessToken) error {
+	authrTokenKey := ctx.authrTokenCacheKey(token.Appid)
+	if err := ctx.Cache.SetString(authrTokenKey, token.AccessToken, CacheExpires(token.ExpiresIn)); err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		return nil
+	}
+	return ctx.componentStore().SetRefreshToken(ctx.AppID, token.Appid, token.RefreshToken)
+}
 
-	ret := &AuthrAccessToken{}
-	if err := json.Unmarshal(body, ret); err != nil {
+// GetAuthrAccessToken 获取授权方AccessToken，过期时使用保存的refresh_token刷新
+func (ctx *Context) GetAuthrAccessToken(appid string) (string, error) {
+	authrTokenKey := ctx.authrTokenCacheKey(appid)
+	if accessToken := ctx.Cache.GetString(authrTokenKey); accessToken != "" {
+		return accessToken, nil
+	}
+	token, err := ctx.refreshAuthrTokenFromStore(appid)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+//refreshAuthrTokenFromStore 使用保存的refresh_token刷新授权方的access_token
+func (ctx *Context) refreshAuthrTokenFromStore(appid string) (*AuthrAccessToken, error) {
+	refreshToken, err := ctx.componentStore().GetRefreshToken(ctx.AppID, appid)
+	if err != nil {
 		return nil, err
 	}
+	if refreshToken == "" {
+		return nil, fmt.Errorf("cannot refresh authorizer %s access token: authorizer_refresh_token not found", appid)
+	}
+	return ctx.RefreshAuthrToken(appid, refreshToken)
+}
 
-	authrTokenKey := fmt.Sprintf(define.ComponentAccessTokenCacheKey, appid)
-	ctx.Cache.SetString(authrTokenKey, ret.AccessToken, time.Minute*80)
+//refreshInvalidAuthrToken 授权方的access_token失效时删除缓存并使用refresh_token重新获取，
+//若已被其他请求刷新则直接返回新的token，invalidToken不是由当前缓存签发时返回ok=false
+func (ctx *Context) refreshInvalidAuthrToken(appid, invalidToken string) (string, bool, error) {
+	authrTokenKey := ctx.authrTokenCacheKey(appid)
+	invalidTokenKey := fmt.Sprintf(define.InvalidAuthorizerAccessTokenCacheKey, ctx.AppID, appid)
+	cachedToken := ctx.Cache.GetString(authrTokenKey)
+	switch {
+	case cachedToken == invalidToken:
+		//缓存中的token已失效，删除并记录后重新获取
+		if err := ctx.Cache.Delete(authrTokenKey); err != nil {
+			return "", false, err
+		}
+		if err := ctx.Cache.SetString(invalidTokenKey, invalidToken, 10*time.Minute); err != nil {
+			return "", false, err
+		}
+	case ctx.Cache.GetString(invalidTokenKey) == invalidToken:
+		//已被其他请求判定为失效，新token已写入缓存时直接使用
+		if cachedToken != "" {
+			return cachedToken, true, nil
+		}
+	default:
+		//不是授权方的authorizer_access_token，如网页授权的access_token
+		return "", false, nil
+	}
+	accessToken, err := ctx.GetAuthrAccessToken(appid)
+	return accessToken, true, err
+}
 
-	return ret, nil
+// RemoveAuthorizer 删除授权方的access_token及refresh_token，用于授权方取消授权后
+func (ctx *Context) RemoveAuthorizer(appid string) error {
+	if err := ctx.Cache.Delete(ctx.authrTokenCacheKey(appid)); err != nil {
+		return err
+	}
+	return ctx.componentStore().DeleteRefreshToken(ctx.AppID, appid)
 }
 
-// GetAuthrAccessToken 获取授权方AccessToken
-func (ctx *Context) GetAuthrAccessToken(appid string) (string, error) {
-	authrTokenKey := fmt.Sprintf(define.ComponentAccessTokenCacheKey, appid)
-	accessToken := ctx.Cache.GetString(authrTokenKey)
-	if accessToken == "" {
-		return "", fmt.Errorf("cannot get authorizer %s access token", appid)
+// ComponentAppID 通过AuthorizerContext创建时返回第三方平台的appid，否则返回空
+func (ctx *Context) ComponentAppID() string {
+	if ctx.component == nil {
+		return ""
+	}
+	return ctx.component.AppID
+}
+
+//boundComponent 返回绑定了ctx的context.Context的第三方平台Context，使刷新authorizer_access_token的请求受同样的取消和超时控制
+func (ctx *Context) boundComponent() *Context {
+	if ctx.stdCtx == nil {
+		return ctx.component
+	}
+	return ctx.component.WithContext(ctx.stdCtx)
+}
+
+// AuthorizerContext 返回代授权方appid调用接口的Context，access_token使用授权方的authorizer_access_token并自动刷新，
+// Token、EncodingAESKey等仍为第三方平台的配置
+func (ctx *Context) AuthorizerContext(appid string) *Context {
+	authrCtx := new(Context)
+	*authrCtx = *ctx
+	authrCtx.AppID = appid
+	authrCtx.AppSecret = ""
+	authrCtx.AccessTokenURL = ""
+	authrCtx.StableAccessToken = false
+	authrCtx.accessTokenFunc = nil
+	authrCtx.component = ctx
+	authrCtx.authorizerLocks = nil
+	locks := ctx.authorizerLock(appid)
+	authrCtx.SetAccessTokenLock(locks.accessToken)
+	authrCtx.SetJsAPITicketLock(locks.jsAPITicket)
+	return authrCtx
+}
+
+//authorizerLocks 授权方Context的读写锁，同一个授权方appid共用
+type authorizerLocks struct {
+	accessToken *sync.RWMutex
+	jsAPITicket *sync.RWMutex
+}
+
+//SetAuthorizerLocks 设置按授权方appid保存读写锁的sync.Map，通过WithContext等复制的Context共用同一个map，
+//NewWechat时已初始化；未设置时在第一次调用AuthorizerContext时创建，此前复制的Context不共用
+func (ctx *Context) SetAuthorizerLocks(locks *sync.Map) {
+	ctx.authorizerLocks = locks
+}
+
+//authorizerLocksMu 保护未通过SetAuthorizerLocks设置时authorizerLocks的初始化
+var authorizerLocksMu sync.Mutex
+
+//authorizerLock 返回授权方appid的读写锁，多次调用AuthorizerContext时在进程内互斥
+func (ctx *Context) authorizerLock(appid string) *authorizerLocks {
+	authorizerLocksMu.Lock()
+	if ctx.authorizerLocks == nil {
+		ctx.authorizerLocks = new(sync.Map)
 	}
-	return accessToken, nil
+	lockMap := ctx.authorizerLocks
+	authorizerLocksMu.Unlock()
+	locks, _ := lockMap.LoadOrStore(appid, &authorizerLocks{accessToken: new(sync.RWMutex), jsAPITicket: new(sync.RWMutex)})
+	return locks.(*authorizerLocks)
 }
 
 // AuthorizerInfo 授权方详细信息
@@ -205,7 +413,7 @@ func (ctx *Context) GetAuthrInfo(appid string) (*AuthorizerInfo, *AuthBaseInfo,
 	}
 
 	uri := fmt.Sprintf(getComponentInfoURL, cat)
-	body, err := util.PostJSON(uri, req)
+	body, err := ctx.PostJSON(uri, req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,99 @@
+package context
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/define"
+)
+
+func TestAuthorizerContextLocks(t *testing.T) {
+	ctx := &Context{AppID: "component_appid", Cache: cache.NewMemory()}
+	ctx.SetAccessTokenLock(new(sync.RWMutex))
+
+	a1, a2, b := ctx.AuthorizerContext("wxa"), ctx.AuthorizerContext("wxa"), ctx.AuthorizerContext("wxb")
+	if a1.accessTokenLock != a2.accessTokenLock || a1.jsAPITicketLock != a2.jsAPITicketLock {
+		t.Error("expect the same authorizer appid to share locks")
+	}
+	if a1.accessTokenLock == b.accessTokenLock || a1.accessTokenLock == ctx.accessTokenLock {
+		t.Error("expect different authorizers to use different locks")
+	}
+
+	//AuthorizerContext之前通过WithContext复制的Context共用同一组锁
+	shared := &Context{AppID: "component_appid", Cache: cache.NewMemory()}
+	shared.SetAuthorizerLocks(new(sync.Map))
+	copied := shared.WithContext(stdcontext.Background())
+	if shared.AuthorizerContext("wxa").accessTokenLock != copied.AuthorizerContext("wxa").accessTokenLock {
+		t.Error("expect copies made before AuthorizerContext to share authorizer locks")
+	}
+
+	if err := ctx.saveAuthrToken(&AuthrAccessToken{Appid: "wxa", AccessToken: "authorizer_token", ExpiresIn: 7200}); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Cache.GetString(fmt.Sprintf(define.AuthorizerAccessTokenCacheKey, "component_appid", "wxa")) != "authorizer_token" {
+		t.Error("expect authorizer token cached under AuthorizerAccessTokenCacheKey")
+	}
+	if ctx.Cache.IsExist(fmt.Sprintf(define.ComponentAccessTokenCacheKey, "wxa")) {
+		t.Error("authorizer token must not share the component_access_token key")
+	}
+}
+
+func TestRefreshInvalidAuthrToken(t *testing.T) {
+	ctx := &Context{AppID: "component_appid", Cache: cache.NewMemory()}
+	if err := ctx.saveAuthrToken(&AuthrAccessToken{Appid: "wxa", AccessToken: "authorizer_token", ExpiresIn: 7200}); err != nil {
+		t.Fatal(err)
+	}
+
+	//网页授权等其他来源的token不替换
+	if token, ok, err := ctx.refreshInvalidAuthrToken("wxa", "sns_token"); ok || token != "" || err != nil {
+		t.Errorf("expect foreign token ignored, got %q %v %v", token, ok, err)
+	}
+
+	//已被其他请求刷新
+	if err := ctx.Cache.SetString(fmt.Sprintf(define.InvalidAuthorizerAccessTokenCacheKey, "component_appid", "wxa"), "stale_token", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if token, ok, err := ctx.refreshInvalidAuthrToken("wxa", "stale_token"); !ok || token != "authorizer_token" || err != nil {
+		t.Errorf("expect refreshed token reused, got %q %v %v", token, ok, err)
+	}
+}
+
+func TestAuthorizerContextWithContext(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"authorizer_access_token":"authorizer_token","expires_in":7200,"authorizer_refresh_token":"refresh_token"}`))
+	}))
+	defer ts.Close()
+
+	ctx := &Context{AppID: "component_appid", Cache: cache.NewMemory(), APIHost: ts.URL}
+	ctx.SetAccessTokenLock(new(sync.RWMutex))
+	if err := ctx.Cache.SetString(fmt.Sprintf(define.ComponentAccessTokenCacheKey, "component_appid"), "component_token", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.componentStore().SetRefreshToken("component_appid", "wxa", "refresh_token"); err != nil {
+		t.Fatal(err)
+	}
+
+	//绑定的context已取消时，刷新authorizer_access_token的请求也被取消
+	cancelled, cancel := stdcontext.WithCancel(stdcontext.Background())
+	cancel()
+	if _, err := ctx.AuthorizerContext("wxa").WithContext(cancelled).GetAccessToken(); !errors.Is(err, stdcontext.Canceled) {
+		t.Errorf("expect context.Canceled, got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("expect no request with cancelled context, got %d", n)
+	}
+
+	if token, err := ctx.AuthorizerContext("wxa").WithContext(stdcontext.Background()).GetAccessToken(); err != nil || token != "authorizer_token" {
+		t.Errorf("expect authorizer token, got %q %v", token, err)
+	}
+}
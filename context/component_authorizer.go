@@ -0,0 +1,257 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/dcsunny/wechat/define"
+)
+
+const (
+	componentLoginPageURL = "https://mp.weixin.qq.com/cgi-bin/componentloginpage"
+	bindComponentURL      = "https://open.weixin.qq.com/wxaopen/safe/bindcomponent"
+
+	// maxAuthorizerListCount 拉取授权方列表每页的最大数量
+	maxAuthorizerListCount = 500
+)
+
+// AuthType 授权页面展示的授权方帐号类型
+type AuthType int
+
+const (
+	// AuthTypeOfficialAccount 仅展示公众号
+	AuthTypeOfficialAccount AuthType = 1
+	// AuthTypeMiniProgram 仅展示小程序
+	AuthTypeMiniProgram AuthType = 2
+	// AuthTypeAll 公众号和小程序都展示
+	AuthTypeAll AuthType = 3
+)
+
+// AuthorizerOption 授权方的选项名
+type AuthorizerOption string
+
+const (
+	// OptionLocationReport 地理位置上报，0不上报 1进入会话时上报 2每5秒上报
+	OptionLocationReport AuthorizerOption = "location_report"
+	// OptionVoiceRecognize 语音识别，0关闭 1开启
+	OptionVoiceRecognize AuthorizerOption = "voice_recognize"
+	// OptionCustomerService 多客服，0关闭 1开启
+	OptionCustomerService AuthorizerOption = "customer_service"
+)
+
+// PreAuthOption 授权页面的可选参数
+type PreAuthOption struct {
+	// AuthType 展示的帐号类型，为0时使用微信的默认值
+	AuthType AuthType
+	// BizAppID 指定授权唯一的公众号或小程序
+	BizAppID string
+}
+
+// GetComponentLoginPage 获取PC端授权页面的URL，需要在第三方平台设置的授权发起页域名下跳转，授权完成后跳转到redirectURI
+func (ctx *Context) GetComponentLoginPage(redirectURI string, opt *PreAuthOption) (string, error) {
+	query, err := ctx.preAuthQuery(redirectURI, opt)
+	if err != nil {
+		return "", err
+	}
+	return componentLoginPageURL + "?" + query.Encode(), nil
+}
+
+// GetBindComponentURL 获取移动端授权链接，需要在微信客户端内打开，授权完成后跳转到redirectURI
+func (ctx *Context) GetBindComponentURL(redirectURI string, opt *PreAuthOption) (string, error) {
+	query, err := ctx.preAuthQuery(redirectURI, opt)
+	if err != nil {
+		return "", err
+	}
+	query.Set("action", "bindcomponent")
+	query.Set("no_scan", "1")
+	return bindComponentURL + "?" + query.Encode() + "#wechat_redirect", nil
+}
+
+// preAuthQuery 获取预授权码并生成授权页面的参数
+func (ctx *Context) preAuthQuery(redirectURI string, opt *PreAuthOption) (url.Values, error) {
+	preCode, err := ctx.GetPreCode()
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("component_appid", ctx.AppID)
+	query.Set("pre_auth_code", preCode)
+	query.Set("redirect_uri", redirectURI)
+	if opt != nil {
+		if opt.AuthType != 0 {
+			query.Set("auth_type", strconv.Itoa(int(opt.AuthType)))
+		}
+		if opt.BizAppID != "" {
+			query.Set("biz_appid", opt.BizAppID)
+		}
+	}
+	return query, nil
+}
+
+// AuthorizerListItem 授权方列表中的授权方
+type AuthorizerListItem struct {
+	AuthorizerAppid string `json:"authorizer_appid"`
+	RefreshToken    string `json:"refresh_token"`
+	AuthTime        int64  `json:"auth_time"`
+}
+
+// AuthorizerList 授权方列表
+type AuthorizerList struct {
+	TotalCount int                  `json:"total_count"`
+	List       []AuthorizerListItem `json:"list"`
+}
+
+// GetAuthorizerList 拉取已授权的帐号列表，offset从0开始，count最大为500
+func (ctx *Context) GetAuthorizerList(offset, count int) (*AuthorizerList, error) {
+	cat, err := ctx.GetComponentAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	req := map[string]interface{}{
+		"component_appid": ctx.AppID,
+		"offset":          offset,
+		"count":           count,
+	}
+	body, err := ctx.PostJSON(fmt.Sprintf(getAuthorizerListURL, cat), req)
+	if err != nil {
+		return nil, err
+	}
+	if err := define.DecodeWithCommonError(body, "GetAuthorizerList"); err != nil {
+		return nil, err
+	}
+	ret := new(AuthorizerList)
+	if err := json.Unmarshal(body, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// AuthorizerIterator 按页拉取全部授权方
+//
+//	iter := ctx.IterAuthorizers(0)
+//	for iter.Next() {
+//		item := iter.Authorizer()
+//	}
+//	if err := iter.Err(); err != nil {
+//	}
+type AuthorizerIterator struct {
+	ctx      *Context
+	pageSize int
+	offset   int
+	total    int
+	page     []AuthorizerListItem
+	current  AuthorizerListItem
+	done     bool
+	err      error
+}
+
+// IterAuthorizers 返回遍历全部授权方的迭代器，pageSize为每页拉取的数量，为0或超过500时使用500
+func (ctx *Context) IterAuthorizers(pageSize int) *AuthorizerIterator {
+	if pageSize <= 0 || pageSize > maxAuthorizerListCount {
+		pageSize = maxAuthorizerListCount
+	}
+	return &AuthorizerIterator{ctx: ctx, pageSize: pageSize}
+}
+
+// Next 移动到下一个授权方，没有更多授权方或拉取失败时返回false
+func (iter *AuthorizerIterator) Next() bool {
+	if len(iter.page) == 0 {
+		if iter.done || iter.err != nil {
+			return false
+		}
+		list, err := iter.ctx.GetAuthorizerList(iter.offset, iter.pageSize)
+		if err != nil {
+			iter.err = err
+			return false
+		}
+		iter.page = list.List
+		iter.total = list.TotalCount
+		iter.offset += len(list.List)
+		iter.done = len(list.List) < iter.pageSize || iter.offset >= list.TotalCount
+		if len(iter.page) == 0 {
+			return false
+		}
+	}
+	iter.current = iter.page[0]
+	iter.page = iter.page[1:]
+	return true
+}
+
+// Authorizer 返回当前的授权方
+func (iter *AuthorizerIterator) Authorizer() AuthorizerListItem {
+	return iter.current
+}
+
+// Total 返回授权方总数，拉取第一页后有效
+func (iter *AuthorizerIterator) Total() int {
+	return iter.total
+}
+
+// Err 返回拉取失败的错误
+func (iter *AuthorizerIterator) Err() error {
+	return iter.err
+}
+
+// GetAuthorizerOption 获取授权方的选项设置信息
+func (ctx *Context) GetAuthorizerOption(appid string, option AuthorizerOption) (string, error) {
+	cat, err := ctx.GetComponentAccessToken()
+	if err != nil {
+		return "", err
+	}
+	req := map[string]string{
+		"component_appid":  ctx.AppID,
+		"authorizer_appid": appid,
+		"option_name":      string(option),
+	}
+	body, err := ctx.PostJSON(fmt.Sprintf(getComponentConfigURL, cat), req)
+	if err != nil {
+		return "", err
+	}
+	if err := define.DecodeWithCommonError(body, "GetAuthorizerOption"); err != nil {
+		return "", err
+	}
+	var ret struct {
+		OptionValue string `json:"option_value"`
+	}
+	if err := json.Unmarshal(body, &ret); err != nil {
+		return "", err
+	}
+	return ret.OptionValue, nil
+}
+
+// SetAuthorizerOption 设置授权方的选项信息
+func (ctx *Context) SetAuthorizerOption(appid string, option AuthorizerOption, value string) error {
+	cat, err := ctx.GetComponentAccessToken()
+	if err != nil {
+		return err
+	}
+	req := map[string]string{
+		"component_appid":  ctx.AppID,
+		"authorizer_appid": appid,
+		"option_name":      string(option),
+		"option_value":     value,
+	}
+	body, err := ctx.PostJSON(fmt.Sprintf(setComponentConfigURL, cat), req)
+	if err != nil {
+		return err
+	}
+	return define.DecodeWithCommonError(body, "SetAuthorizerOption")
+}
+
+// ClearComponentQuota 清零第三方平台的接口调用次数
+func (ctx *Context) ClearComponentQuota() error {
+	cat, err := ctx.GetComponentAccessToken()
+	if err != nil {
+		return err
+	}
+	req := map[string]string{
+		"component_appid": ctx.AppID,
+	}
+	body, err := ctx.PostJSON(fmt.Sprintf(componentClearQuotaURL, cat), req)
+	if err != nil {
+		return err
+	}
+	return define.DecodeWithCommonError(body, "ClearComponentQuota")
+}
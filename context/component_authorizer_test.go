@@ -0,0 +1,141 @@
+package context_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dcsunny/wechat"
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/wechattest"
+)
+
+func TestComponentAuthorizer(t *testing.T) {
+	s := wechattest.NewServer()
+	defer s.Close()
+	wc := s.NewWechat(&wechat.Config{AppID: "component_appid", AppSecret: "component_secret", Cache: cache.NewMemory()})
+
+	if _, err := wc.Context.GetComponentAccessToken(); err == nil {
+		t.Error("expect error before component_verify_ticket received")
+	}
+	if err := wc.Context.SetComponentVerifyTicket("ticket@@@"); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := wc.Context.GetComponentAccessToken(); err != nil || token != wechattest.ComponentAccessToken {
+		t.Fatalf("expect component_access_token fetched with stored ticket, got %q %v", token, err)
+	}
+	if token, err := wc.Context.GetComponentAccessToken(); err != nil || token != wechattest.ComponentAccessToken || len(s.Requests("/cgi-bin/component/api_component_token")) != 1 {
+		t.Errorf("expect cached component_access_token, got %q %v", token, err)
+	}
+
+	info, err := wc.Context.QueryAuthCode("auth_code")
+	if err != nil || info.Appid != wechattest.AuthorizerAppID {
+		t.Fatalf("QueryAuthCode: %+v %v", info, err)
+	}
+
+	authorizer := wc.Authorizer(wechattest.AuthorizerAppID)
+	if _, err := authorizer.GetUser().GetUserInfo("OPENID"); err != nil {
+		t.Errorf("expect authorizer api call succeed, got %v", err)
+	}
+
+	//authorizer_access_token失效后使用保存的refresh_token刷新
+	s.InvalidateAccessToken()
+	if _, err := authorizer.GetUser().GetUserInfo("OPENID"); err != nil {
+		t.Errorf("expect retry with refreshed authorizer token, got %v", err)
+	}
+	refreshes := s.Requests("/cgi-bin/component/api_authorizer_token")
+	if len(refreshes) != 1 {
+		t.Fatalf("expect authorizer token refreshed once, got %d", len(refreshes))
+	}
+	if len(s.Requests("/cgi-bin/token")) != 0 {
+		t.Error("authorizer must not request access_token with appsecret")
+	}
+
+	if err := wc.Context.RemoveAuthorizer(wechattest.AuthorizerAppID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wc.Context.GetAuthrAccessToken(wechattest.AuthorizerAppID); err == nil {
+		t.Error("expect error after authorizer removed")
+	}
+}
+
+func TestComponentManagement(t *testing.T) {
+	s := wechattest.NewServer()
+	defer s.Close()
+	wc := s.NewWechat(&wechat.Config{AppID: "component_appid", AppSecret: "component_secret", Cache: cache.NewMemory()})
+	if err := wc.Context.SetComponentVerifyTicket("ticket"); err != nil {
+		t.Fatal(err)
+	}
+
+	//共5个授权方，每页2个
+	s.Handle("/cgi-bin/component/api_get_authorizer_list", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Offset int `json:"offset"`
+			Count  int `json:"count"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var list []interface{}
+		for i := req.Offset; i < 5 && i < req.Offset+req.Count; i++ {
+			list = append(list, map[string]interface{}{"authorizer_appid": fmt.Sprintf("wx%d", i), "refresh_token": "token"})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total_count": 5, "list": list})
+	})
+	iter := wc.Context.IterAuthorizers(2)
+	var appids []string
+	for iter.Next() {
+		appids = append(appids, iter.Authorizer().AuthorizerAppid)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(appids, ",") != "wx0,wx1,wx2,wx3,wx4" || iter.Total() != 5 {
+		t.Errorf("unexpected authorizers %v total %d", appids, iter.Total())
+	}
+	if pages := len(s.Requests("/cgi-bin/component/api_get_authorizer_list")); pages != 3 {
+		t.Errorf("expect 3 pages, got %d", pages)
+	}
+
+	s.Reset("/cgi-bin/component/api_get_authorizer_list")
+	s.Enqueue("/cgi-bin/component/api_get_authorizer_list", wechattest.Error(61003, "component is not authorized by this account"))
+	iter = wc.Context.IterAuthorizers(0)
+	if iter.Next() || iter.Err() == nil {
+		t.Error("expect iterator stopped with error")
+	}
+
+	if value, err := wc.Context.GetAuthorizerOption(wechattest.AuthorizerAppID, context.OptionVoiceRecognize); err != nil || value != "1" {
+		t.Errorf("GetAuthorizerOption: %q %v", value, err)
+	}
+	if err := wc.Context.SetAuthorizerOption(wechattest.AuthorizerAppID, context.OptionVoiceRecognize, "0"); err != nil {
+		t.Error(err)
+	}
+	if body := string(s.Requests("/cgi-bin/component/api_set_authorizer_option")[0].Body); !strings.Contains(body, `"option_value":"0"`) {
+		t.Errorf("unexpected set option request %s", body)
+	}
+	if err := wc.Context.ClearComponentQuota(); err != nil {
+		t.Error(err)
+	}
+
+	pcURL, err := wc.Context.GetComponentLoginPage("https://example.com/auth", &context.PreAuthOption{AuthType: context.AuthTypeMiniProgram, BizAppID: "wxbiz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(pcURL)
+	q := u.Query()
+	if u.Host != "mp.weixin.qq.com" || q.Get("pre_auth_code") != wechattest.PreAuthCode || q.Get("component_appid") != "component_appid" ||
+		q.Get("redirect_uri") != "https://example.com/auth" || q.Get("auth_type") != "2" || q.Get("biz_appid") != "wxbiz" {
+		t.Errorf("unexpected componentloginpage url %s", pcURL)
+	}
+	mobileURL, err := wc.Context.GetBindComponentURL("https://example.com/auth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ = url.Parse(mobileURL)
+	q = u.Query()
+	if q.Get("action") != "bindcomponent" || q.Get("pre_auth_code") != wechattest.PreAuthCode || q.Get("auth_type") != "" || u.Fragment != "wechat_redirect" {
+		t.Errorf("unexpected bindcomponent url %s", mobileURL)
+	}
+}
@@ -0,0 +1,69 @@
+package context
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/define"
+)
+
+const (
+	//verifyTicketExpiration component_verify_ticket有效期为12小时
+	verifyTicketExpiration = 12 * time.Hour
+	//refreshTokenExpiration authorizer_refresh_token在取消授权前一直有效，缓存不支持永久保存时使用较长的时间
+	refreshTokenExpiration = 10 * 365 * 24 * time.Hour
+	//memcacheRefreshTokenExpiration memcache将超过30天的过期时间视为unix时间戳，因此不能超过30天，
+	//每次刷新authorizer_access_token时重新保存
+	memcacheRefreshTokenExpiration = 30 * 24 * time.Hour
+)
+
+//ComponentStore 保存第三方平台的component_verify_ticket及授权方的authorizer_refresh_token，
+//refresh_token丢失后需要授权方重新授权，生产环境建议使用持久化的存储
+type ComponentStore interface {
+	GetVerifyTicket(componentAppID string) (string, error)
+	SetVerifyTicket(componentAppID, ticket string) error
+	GetRefreshToken(componentAppID, authorizerAppID string) (string, error)
+	SetRefreshToken(componentAppID, authorizerAppID, refreshToken string) error
+	DeleteRefreshToken(componentAppID, authorizerAppID string) error
+}
+
+//NewCacheComponentStore 使用cache.Cache保存，Context.ComponentStore为空时使用Context.Cache
+func NewCacheComponentStore(c cache.Cache) ComponentStore {
+	return &cacheComponentStore{cache: c}
+}
+
+type cacheComponentStore struct {
+	cache cache.Cache
+}
+
+func (s *cacheComponentStore) GetVerifyTicket(componentAppID string) (string, error) {
+	return s.cache.GetString(fmt.Sprintf(define.ComponentVerifyTicketCacheKey, componentAppID)), nil
+}
+
+func (s *cacheComponentStore) SetVerifyTicket(componentAppID, ticket string) error {
+	return s.cache.SetString(fmt.Sprintf(define.ComponentVerifyTicketCacheKey, componentAppID), ticket, verifyTicketExpiration)
+}
+
+func (s *cacheComponentStore) GetRefreshToken(componentAppID, authorizerAppID string) (string, error) {
+	return s.cache.GetString(fmt.Sprintf(define.AuthorizerRefreshTokenCacheKey, componentAppID, authorizerAppID)), nil
+}
+
+func (s *cacheComponentStore) SetRefreshToken(componentAppID, authorizerAppID, refreshToken string) error {
+	expiration := refreshTokenExpiration
+	if _, ok := s.cache.(*cache.Memcache); ok {
+		expiration = memcacheRefreshTokenExpiration
+	}
+	return s.cache.SetString(fmt.Sprintf(define.AuthorizerRefreshTokenCacheKey, componentAppID, authorizerAppID), refreshToken, expiration)
+}
+
+func (s *cacheComponentStore) DeleteRefreshToken(componentAppID, authorizerAppID string) error {
+	return s.cache.Delete(fmt.Sprintf(define.AuthorizerRefreshTokenCacheKey, componentAppID, authorizerAppID))
+}
+
+func (ctx *Context) componentStore() ComponentStore {
+	if ctx.ComponentStore != nil {
+		return ctx.ComponentStore
+	}
+	return NewCacheComponentStore(ctx.Cache)
+}
@@ -0,0 +1,138 @@
+package context
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat/cache"
+)
+
+//fakeMemcached 实现memcached文本协议的set、get，按memcached的规则处理过期时间：超过30天时视为unix时间戳
+type fakeMemcached struct {
+	net.Listener
+
+	mu    sync.Mutex
+	items map[string]fakeMemcachedItem
+}
+
+type fakeMemcachedItem struct {
+	value   []byte
+	expired time.Time
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &fakeMemcached{Listener: ln, items: make(map[string]fakeMemcachedItem)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go m.serve(conn)
+		}
+	}()
+	return m
+}
+
+func (m *fakeMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "set":
+			//set <key> <flags> <exptime> <bytes>
+			exptime, _ := strconv.ParseInt(fields[3], 10, 64)
+			size, _ := strconv.Atoi(fields[4])
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(rw, data); err != nil {
+				return
+			}
+			item := fakeMemcachedItem{value: data[:size]}
+			switch {
+			case exptime > 30*24*3600:
+				item.expired = time.Unix(exptime, 0)
+			case exptime > 0:
+				item.expired = time.Now().Add(time.Duration(exptime) * time.Second)
+			}
+			m.mu.Lock()
+			m.items[fields[1]] = item
+			m.mu.Unlock()
+			rw.WriteString("STORED\r\n")
+		case "get", "gets":
+			m.mu.Lock()
+			for _, key := range fields[1:] {
+				item, ok := m.items[key]
+				if !ok || (!item.expired.IsZero() && time.Now().After(item.expired)) {
+					continue
+				}
+				fmt.Fprintf(rw, "VALUE %s 0 %d 0\r\n%s\r\n", key, len(item.value), item.value)
+			}
+			m.mu.Unlock()
+			rw.WriteString("END\r\n")
+		default:
+			rw.WriteString("ERROR\r\n")
+		}
+		rw.Flush()
+	}
+}
+
+func TestCacheComponentStoreMemcache(t *testing.T) {
+	server := newFakeMemcached(t)
+	defer server.Close()
+
+	store := NewCacheComponentStore(cache.NewMemcache(server.Addr().String()))
+	if err := store.SetRefreshToken("component_appid", "authorizer_appid", "refresh_token"); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := store.GetRefreshToken("component_appid", "authorizer_appid"); err != nil || token != "refresh_token" {
+		t.Errorf("expect refresh_token kept in memcache, got %q %v", token, err)
+	}
+	if err := store.SetVerifyTicket("component_appid", "ticket"); err != nil {
+		t.Fatal(err)
+	}
+	if ticket, err := store.GetVerifyTicket("component_appid"); err != nil || ticket != "ticket" {
+		t.Errorf("expect verify ticket kept in memcache, got %q %v", ticket, err)
+	}
+}
+
+//timeoutCache 记录写入时的过期时间
+type timeoutCache struct {
+	cache.Cache
+	timeouts map[string]time.Duration
+}
+
+func (c *timeoutCache) SetString(key string, val string, timeout time.Duration) error {
+	c.timeouts[key] = timeout
+	return c.Cache.SetString(key, val, timeout)
+}
+
+func TestCacheComponentStoreExpiration(t *testing.T) {
+	//非memcache的缓存不受30天的限制，长期无活动的授权方不会丢失refresh_token
+	c := &timeoutCache{Cache: cache.NewMemory(), timeouts: make(map[string]time.Duration)}
+	store := NewCacheComponentStore(c)
+	if err := store.SetRefreshToken("component_appid", "authorizer_appid", "refresh_token"); err != nil {
+		t.Fatal(err)
+	}
+	if timeout := c.timeouts["authorizer_refresh_token_component_appid_authorizer_appid"]; timeout != refreshTokenExpiration {
+		t.Errorf("expect long refresh_token expiration, got %v (%v)", timeout, c.timeouts)
+	}
+}
@@ -1,6 +1,7 @@
 package context
 
 import (
+	stdcontext "context"
 	"net/http"
 	"sync"
 
@@ -20,11 +21,24 @@ type Context struct {
 	PayKeyPEMBlock  string
 
 	AccessTokenURL string
+	//JsAPITicketURL 获取jsapi_ticket的地址，设置后从该地址(如relay中控服务)获取，不再使用access_token请求微信
+	JsAPITicketURL string
+	//StableAccessToken 使用getStableAccessToken(cgi-bin/stable_token)获取access_token，多个服务共用AppID时不会互相覆盖；
+	//设置了AccessTokenURL时从AccessTokenURL获取，不使用该配置
+	StableAccessToken bool
 
-	Cache cache.Cache
+	//HTTPClient 调用微信接口使用的client，为空时使用http.DefaultClient
+	HTTPClient *http.Client
+	//APIHost 微信接口域名，为空时使用define.APIHost，可指向代理或本地测试服务
+	APIHost string
+	//PayAPIHost 微信支付接口域名，为空时使用define.PayAPIHost
+	PayAPIHost string
 
-	Writer  http.ResponseWriter
-	Request *http.Request
+	Cache cache.Cache
+	//Locker 多实例部署时用于保证只有一个实例刷新access_token等凭据，为空时仅在进程内加锁
+	Locker cache.Locker
+	//ComponentStore 第三方平台保存component_verify_ticket及授权方refresh_token的存储，为空时使用Cache
+	ComponentStore ComponentStore
 
 	//accessTokenLock 读写锁 同一个AppID一个
 	accessTokenLock *sync.RWMutex
@@ -34,21 +48,15 @@ type Context struct {
 
 	//accessTokenFunc 自定义获取 access token 的方法
 	accessTokenFunc GetAccessTokenFunc
-}
 
-// Query returns the keyed url query value if it exists
-func (ctx *Context) Query(key string) string {
-	value, _ := ctx.GetQuery(key)
-	return value
-}
+	//component 通过AuthorizerContext创建时为第三方平台的Context，access_token使用授权方的authorizer_access_token
+	component *Context
+
+	//authorizerLocks 第三方平台Context中按授权方appid保存的*authorizerLocks
+	authorizerLocks *sync.Map
 
-// GetQuery is like Query(), it returns the keyed url query value
-func (ctx *Context) GetQuery(key string) (string, bool) {
-	req := ctx.Request
-	if values, ok := req.URL.Query()[key]; ok && len(values) > 0 {
-		return values[0], true
-	}
-	return "", false
+	//stdCtx 通过WithContext绑定的context.Context
+	stdCtx stdcontext.Context
 }
 
 // SetJsAPITicketLock 设置jsAPITicket的lock
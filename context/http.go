@@ -0,0 +1,151 @@
+package context
+
+import (
+	"bytes"
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dcsunny/wechat/define"
+	"github.com/dcsunny/wechat/util"
+)
+
+//WithContext 返回绑定了c的Context副本，副本发起的请求及获取access_token都受c的取消和超时控制
+func (ctx *Context) WithContext(c stdcontext.Context) *Context {
+	if c == nil {
+		panic("nil context")
+	}
+	ctx2 := new(Context)
+	*ctx2 = *ctx
+	ctx2.stdCtx = c
+	return ctx2
+}
+
+//StdContext 返回绑定的context.Context，未绑定时返回context.Background()
+func (ctx *Context) StdContext() stdcontext.Context {
+	if ctx.stdCtx != nil {
+		return ctx.stdCtx
+	}
+	return stdcontext.Background()
+}
+
+//ResolveURL 将uri中默认的微信接口域名替换为配置的APIHost/PayAPIHost
+func (ctx *Context) ResolveURL(uri string) string {
+	if ctx.APIHost != "" && strings.HasPrefix(uri, define.APIHost) {
+		return strings.TrimRight(ctx.APIHost, "/") + strings.TrimPrefix(uri, define.APIHost)
+	}
+	if ctx.PayAPIHost != "" && strings.HasPrefix(uri, define.PayAPIHost) {
+		return strings.TrimRight(ctx.PayAPIHost, "/") + strings.TrimPrefix(uri, define.PayAPIHost)
+	}
+	return uri
+}
+
+//HTTPGet 使用当前配置的client和域名发起get请求
+func (ctx *Context) HTTPGet(uri string) ([]byte, error) {
+	response, err := util.HTTPGetWithContext(ctx.StdContext(), ctx.ResolveURL(uri), ctx.HTTPClient)
+	if retryURI, ok := ctx.retryURI(uri, response, err); ok {
+		return util.HTTPGetWithContext(ctx.StdContext(), ctx.ResolveURL(retryURI), ctx.HTTPClient)
+	}
+	return response, err
+}
+
+//HTTPPost 使用当前配置的client和域名发起post请求
+func (ctx *Context) HTTPPost(uri string, data string) ([]byte, error) {
+	response, err := util.HTTPPostWithContext(ctx.StdContext(), ctx.ResolveURL(uri), data, ctx.HTTPClient)
+	if retryURI, ok := ctx.retryURI(uri, response, err); ok {
+		return util.HTTPPostWithContext(ctx.StdContext(), ctx.ResolveURL(retryURI), data, ctx.HTTPClient)
+	}
+	return response, err
+}
+
+//PostJSON 使用当前配置的client和域名发起post json请求
+func (ctx *Context) PostJSON(uri string, obj interface{}) ([]byte, error) {
+	response, err := util.PostJSONWithContext(ctx.StdContext(), ctx.ResolveURL(uri), obj, ctx.HTTPClient)
+	if retryURI, ok := ctx.retryURI(uri, response, err); ok {
+		return util.PostJSONWithContext(ctx.StdContext(), ctx.ResolveURL(retryURI), obj, ctx.HTTPClient)
+	}
+	return response, err
+}
+
+//PostJSONWithRespContentType 使用当前配置的client和域名发起post json请求，且返回数据类型
+func (ctx *Context) PostJSONWithRespContentType(uri string, obj interface{}) ([]byte, string, error) {
+	response, contentType, err := util.PostJSONWithRespContentTypeWithContext(ctx.StdContext(), ctx.ResolveURL(uri), obj, ctx.HTTPClient)
+	if !strings.HasPrefix(contentType, "application/json") && !strings.HasPrefix(contentType, "text/plain") {
+		return response, contentType, err
+	}
+	if retryURI, ok := ctx.retryURI(uri, response, err); ok {
+		return util.PostJSONWithRespContentTypeWithContext(ctx.StdContext(), ctx.ResolveURL(retryURI), obj, ctx.HTTPClient)
+	}
+	return response, contentType, err
+}
+
+//PostFile 使用当前配置的client和域名上传文件
+func (ctx *Context) PostFile(fieldname, filename, uri string) ([]byte, error) {
+	response, err := util.PostFileWithContext(ctx.StdContext(), fieldname, filename, ctx.ResolveURL(uri), ctx.HTTPClient)
+	if retryURI, ok := ctx.retryURI(uri, response, err); ok {
+		return util.PostFileWithContext(ctx.StdContext(), fieldname, filename, ctx.ResolveURL(retryURI), ctx.HTTPClient)
+	}
+	return response, err
+}
+
+//PostFileV2 使用当前配置的client和域名上传io.Reader中的文件内容
+func (ctx *Context) PostFileV2(fieldname, filename string, fileReader io.Reader, uri string) ([]byte, error) {
+	//读取到内存中，以便access_token失效时重新上传
+	fileBytes, err := ioutil.ReadAll(fileReader)
+	if err != nil {
+		return nil, err
+	}
+	response, err := util.PostFileV2WithContext(ctx.StdContext(), fieldname, filename, bytes.NewReader(fileBytes), ctx.ResolveURL(uri), ctx.HTTPClient)
+	if retryURI, ok := ctx.retryURI(uri, response, err); ok {
+		return util.PostFileV2WithContext(ctx.StdContext(), fieldname, filename, bytes.NewReader(fileBytes), ctx.ResolveURL(retryURI), ctx.HTTPClient)
+	}
+	return response, err
+}
+
+//PostMultipartForm 使用当前配置的client和域名上传文件或其他多个字段
+func (ctx *Context) PostMultipartForm(fields []util.MultipartFormField, uri string) ([]byte, error) {
+	response, err := util.PostMultipartFormWithContext(ctx.StdContext(), fields, ctx.ResolveURL(uri), ctx.HTTPClient)
+	if retryURI, ok := ctx.retryURI(uri, response, err); ok {
+		return util.PostMultipartFormWithContext(ctx.StdContext(), fields, ctx.ResolveURL(retryURI), ctx.HTTPClient)
+	}
+	return response, err
+}
+
+//retryURI 接口返回access_token无效(40001、42001、40014)时，刷新access_token并返回替换了新token的uri
+func (ctx *Context) retryURI(uri string, response []byte, err error) (string, bool) {
+	if err != nil || len(response) == 0 || response[0] != '{' {
+		return "", false
+	}
+	var commError define.CommonError
+	if json.Unmarshal(response, &commError) != nil || commError.ErrCode == 0 {
+		return "", false
+	}
+	if !errors.Is(define.NewError("", commError.ErrCode, commError.ErrMsg), define.ErrAccessTokenInvalid) {
+		return "", false
+	}
+	u, e := url.Parse(uri)
+	if e != nil {
+		return "", false
+	}
+	query := u.Query()
+	accessToken, ok, e := ctx.RefreshInvalidAccessToken(query.Get("access_token"))
+	if !ok || e != nil {
+		return "", false
+	}
+	query.Set("access_token", accessToken)
+	u.RawQuery = query.Encode()
+	return u.String(), true
+}
+
+//PostXML 使用当前配置的域名发起post xml请求，client为nil时使用配置的HTTPClient
+func (ctx *Context) PostXML(uri string, obj interface{}, client *http.Client) ([]byte, error) {
+	if client == nil {
+		client = ctx.HTTPClient
+	}
+	return util.PostXMLWithContext(ctx.StdContext(), ctx.ResolveURL(uri), obj, client)
+}
@@ -0,0 +1,68 @@
+package context
+
+import (
+	stdcontext "context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContext_ResolveURL(t *testing.T) {
+	ctx := Context{
+		APIHost:    "http://127.0.0.1:8080/",
+		PayAPIHost: "http://127.0.0.1:8081",
+	}
+	if uri := ctx.ResolveURL("https://api.weixin.qq.com/cgi-bin/menu/get?access_token=x"); uri != "http://127.0.0.1:8080/cgi-bin/menu/get?access_token=x" {
+		t.Errorf("unexpected api url %s", uri)
+	}
+	if uri := ctx.ResolveURL("https://api.mch.weixin.qq.com/pay/unifiedorder"); uri != "http://127.0.0.1:8081/pay/unifiedorder" {
+		t.Errorf("unexpected pay url %s", uri)
+	}
+	if uri := ctx.ResolveURL("https://example.com/callback"); uri != "https://example.com/callback" {
+		t.Errorf("unexpected other url %s", uri)
+	}
+}
+
+func TestContext_HTTPGet(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer ts.Close()
+
+	ctx := Context{
+		HTTPClient: ts.Client(),
+		APIHost:    ts.URL,
+	}
+	res, err := ctx.HTTPGet("https://api.weixin.qq.com/cgi-bin/menu/get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "/cgi-bin/menu/get" {
+		t.Errorf("unexpected response %s", res)
+	}
+}
+
+func TestContext_WithContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	ctx := &Context{APIHost: ts.URL}
+	if ctx.StdContext() != stdcontext.Background() {
+		t.Error("expect background context")
+	}
+	c, cancel := stdcontext.WithTimeout(stdcontext.Background(), 50*time.Millisecond)
+	defer cancel()
+	bound := ctx.WithContext(c)
+	if bound == ctx || bound.StdContext() != c {
+		t.Fatal("expect a copy bound to c")
+	}
+	if _, err := bound.HTTPGet("https://api.weixin.qq.com/cgi-bin/menu/get"); err == nil {
+		t.Error("expect deadline exceeded error")
+	}
+}
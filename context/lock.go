@@ -0,0 +1,46 @@
+package context
+
+import (
+	"time"
+
+	"github.com/dcsunny/wechat/util"
+)
+
+const (
+	//refreshLockTTL 刷新锁的过期时间，持有锁的实例异常退出时其他实例最多等待该时长
+	refreshLockTTL = 10 * time.Second
+	//refreshWaitInterval 未获取到锁时轮询缓存的间隔
+	refreshWaitInterval = 100 * time.Millisecond
+)
+
+//RefreshWithLock 在Locker保护下刷新凭据：获取到锁的实例调用fetch从微信服务器获取并写入缓存，
+//其他实例等待并通过get从缓存读取结果，get返回非空时视为已被刷新。未配置Locker时直接调用fetch
+func (ctx *Context) RefreshWithLock(lockKey string, get func() string, fetch func() (string, error)) (string, error) {
+	if ctx.Locker == nil {
+		return fetch()
+	}
+	owner := util.RandomStr(16)
+	for {
+		ok, err := ctx.Locker.Acquire(lockKey, owner, refreshLockTTL)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			defer ctx.Locker.Release(lockKey, owner)
+			//等待锁期间可能已被其他实例刷新
+			if value := get(); value != "" {
+				return value, nil
+			}
+			return fetch()
+		}
+
+		select {
+		case <-ctx.StdContext().Done():
+			return "", ctx.StdContext().Err()
+		case <-time.After(refreshWaitInterval):
+		}
+		if value := get(); value != "" {
+			return value, nil
+		}
+	}
+}
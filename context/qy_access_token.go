@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -54,10 +53,10 @@ func (ctx *Context) GetQyAccessToken() (accessToken string, err error) {
 
 //GetQyAccessTokenFromServer 强制从微信服务器获取token
 func (ctx *Context) GetQyAccessTokenFromServer() (resQyAccessToken ResQyAccessToken, err error) {
-	log.Printf("GetQyAccessTokenFromServer")
+	log.Printf("GetQyAccessToken")
 	url := fmt.Sprintf(qyAccessTokenURL, ctx.AppID, ctx.AppSecret)
 	var body []byte
-	body, err = util.HTTPGet(url)
+	body, err = ctx.HTTPGet(url)
 	if err != nil {
 		return
 	}
@@ -66,7 +65,7 @@ func (ctx *Context) GetQyAccessTokenFromServer() (resQyAccessToken ResQyAccessTo
 		return
 	}
 	if resQyAccessToken.ErrCode != 0 {
-		err = fmt.Errorf("get qy_access_token error : errcode=%v , errormsg=%v", resQyAccessToken.ErrCode, resQyAccessToken.ErrMsg)
+		err = define.NewError("GetQyAccessTokenFromServer", resQyAccessToken.ErrCode, resQyAccessToken.ErrMsg)
 		return
 	}
 
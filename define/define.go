@@ -2,13 +2,36 @@ package define
 
 import (
 	"encoding/json"
-	"fmt"
+)
+
+const (
+	//APIHost 微信公众平台接口的默认域名
+	APIHost = "https://api.weixin.qq.com"
+	//PayAPIHost 微信支付接口的默认域名
+	PayAPIHost = "https://api.mch.weixin.qq.com"
 )
 
 const (
 	AccessTokenCacheKey          = "access_token:%s"
+	InvalidAccessTokenCacheKey   = "invalid_access_token:%s"
 	MiniAccessTokenCacheKey      = "mini_access_token_:%s"
 	ComponentAccessTokenCacheKey = "component_access_token_%s"
+	//ComponentVerifyTicketCacheKey 第三方平台的component_verify_ticket
+	ComponentVerifyTicketCacheKey = "component_verify_ticket_%s"
+	//AuthorizerRefreshTokenCacheKey 第三方平台(component_appid)下授权方(authorizer_appid)的authorizer_refresh_token
+	AuthorizerRefreshTokenCacheKey = "authorizer_refresh_token_%s_%s"
+	//AuthorizerAccessTokenCacheKey 第三方平台(component_appid)下授权方(authorizer_appid)的authorizer_access_token
+	AuthorizerAccessTokenCacheKey = "authorizer_access_token_%s_%s"
+	//InvalidAuthorizerAccessTokenCacheKey 最近失效的authorizer_access_token
+	InvalidAuthorizerAccessTokenCacheKey = "invalid_authorizer_access_token_%s_%s"
+)
+
+const (
+	AccessTokenLockKey          = "access_token_lock:%s"
+	JsAPITicketLockKey          = "jsapi_ticket_lock:%s"
+	ComponentAccessTokenLockKey = "component_access_token_lock_%s"
+	//AuthorizerAccessTokenLockKey 刷新授权方(component_appid、authorizer_appid)authorizer_access_token的锁
+	AuthorizerAccessTokenLockKey = "authorizer_access_token_lock_%s_%s"
 )
 
 // CommonError 微信返回的通用错误json
@@ -25,7 +48,7 @@ func DecodeWithCommonError(response []byte, apiName string) (err error) {
 		return
 	}
 	if commError.ErrCode != 0 {
-		return fmt.Errorf("%s Error , errcode=%d , errmsg=%s", apiName, commError.ErrCode, commError.ErrMsg)
+		return NewError(apiName, commError.ErrCode, commError.ErrMsg)
 	}
 	return nil
 }
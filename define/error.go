@@ -0,0 +1,127 @@
+package define
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	//ErrAccessTokenInvalid access_token无效或已过期，errcode为40001、42001、40014
+	ErrAccessTokenInvalid = errors.New("access_token invalid")
+	//ErrQuotaExceeded 接口调用超过限制，errcode为45009
+	ErrQuotaExceeded = errors.New("api quota exceeded")
+	//ErrSystemBusy 系统繁忙，errcode为-1
+	ErrSystemBusy = errors.New("system busy")
+	//ErrUserRefused 用户拒收消息，errcode为43101
+	ErrUserRefused = errors.New("user refused")
+	//ErrOutOfResponseWindow 回复时间超过限制（用户48小时内未互动），errcode为45015
+	ErrOutOfResponseWindow = errors.New("out of response window")
+	//ErrRiskyContent 内容含有违法违规内容，errcode为87014
+	ErrRiskyContent = errors.New("risky content")
+)
+
+//errCodeClasses errcode与错误类别的对应关系
+var errCodeClasses = map[int64]error{
+	40001: ErrAccessTokenInvalid,
+	42001: ErrAccessTokenInvalid,
+	40014: ErrAccessTokenInvalid,
+	45009: ErrQuotaExceeded,
+	-1:    ErrSystemBusy,
+	43101: ErrUserRefused,
+	45015: ErrOutOfResponseWindow,
+	87014: ErrRiskyContent,
+}
+
+//payErrCodeClasses 微信支付err_code与错误类别的对应关系
+var payErrCodeClasses = map[string]error{
+	"SYSTEMERROR": ErrSystemBusy,
+	"FREQ_LIMIT":  ErrQuotaExceeded,
+}
+
+//Error 微信接口返回的错误，可通过errors.Is判断其所属类别，如errors.Is(err, define.ErrAccessTokenInvalid)
+type Error struct {
+	APIName string
+	ErrCode int64
+	//PayErrCode 微信支付接口返回的err_code，如ORDERPAID；return_code为FAIL时为FAIL
+	PayErrCode string
+	ErrMsg     string
+	RequestID  string
+}
+
+//NewError 根据接口返回的errcode和errmsg生成错误，RequestID从errmsg中的rid解析
+func NewError(apiName string, errCode int64, errMsg string) *Error {
+	return &Error{
+		APIName:   apiName,
+		ErrCode:   errCode,
+		ErrMsg:    errMsg,
+		RequestID: parseRequestID(errMsg),
+	}
+}
+
+//NewPayError 根据微信支付接口返回的err_code和err_code_des生成错误
+func NewPayError(apiName string, errCode string, errMsg string) *Error {
+	return &Error{
+		APIName:    apiName,
+		PayErrCode: errCode,
+		ErrMsg:     errMsg,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.PayErrCode != "" {
+		return fmt.Sprintf("%s Error , err_code=%s , err_code_des=%s", e.APIName, e.PayErrCode, e.ErrMsg)
+	}
+	return fmt.Sprintf("%s Error , errcode=%d , errmsg=%s", e.APIName, e.ErrCode, e.ErrMsg)
+}
+
+//Is 判断错误是否属于target类别
+func (e *Error) Is(target error) bool {
+	if e.PayErrCode != "" {
+		class, ok := payErrCodeClasses[e.PayErrCode]
+		return ok && class == target
+	}
+	class, ok := errCodeClasses[e.ErrCode]
+	return ok && class == target
+}
+
+//parseRequestID 从形如 "invalid credential rid: 5f1e8a2b-xxx" 的errmsg中取出rid
+func parseRequestID(errMsg string) string {
+	i := strings.LastIndex(errMsg, "rid: ")
+	if i < 0 {
+		return ""
+	}
+	rid := errMsg[i+len("rid: "):]
+	if j := strings.IndexAny(rid, " ,"); j >= 0 {
+		rid = rid[:j]
+	}
+	return rid
+}
+
+//payCommonError 微信支付返回的通用字段
+type payCommonError struct {
+	ReturnCode string `xml:"return_code"`
+	ReturnMsg  string `xml:"return_msg"`
+	ResultCode string `xml:"result_code"`
+	ErrCode    string `xml:"err_code"`
+	ErrCodeDes string `xml:"err_code_des"`
+}
+
+//DecodeWithPayError 将微信支付的xml返回值按照return_code、result_code解析，失败时返回*Error
+func DecodeWithPayError(response []byte, apiName string) error {
+	var payError payCommonError
+	if err := xml.Unmarshal(response, &payError); err != nil {
+		return err
+	}
+	if payError.ReturnCode != "SUCCESS" {
+		return NewPayError(apiName, "FAIL", payError.ReturnMsg)
+	}
+	if payError.ResultCode != "SUCCESS" {
+		if payError.ErrCode == "" {
+			payError.ErrCode = "FAIL"
+		}
+		return NewPayError(apiName, payError.ErrCode, payError.ErrCodeDes)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package define
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestError_Is(t *testing.T) {
+	for _, errCode := range []int64{40001, 42001, 40014} {
+		err := fmt.Errorf("wrapped: %w", NewError("GetUserInfo", errCode, "access_token expired"))
+		if !errors.Is(err, ErrAccessTokenInvalid) {
+			t.Errorf("errcode %d should be ErrAccessTokenInvalid", errCode)
+		}
+	}
+	if errors.Is(NewError("Send", 45015, "response out of time limit"), ErrAccessTokenInvalid) {
+		t.Error("45015 should not be ErrAccessTokenInvalid")
+	}
+	if !errors.Is(NewError("Send", 45015, "response out of time limit"), ErrOutOfResponseWindow) {
+		t.Error("45015 should be ErrOutOfResponseWindow")
+	}
+	if errors.Is(NewError("Send", 40003, "invalid openid"), ErrSystemBusy) {
+		t.Error("40003 should not match any class")
+	}
+}
+
+func TestDecodeWithCommonError(t *testing.T) {
+	err := DecodeWithCommonError([]byte(`{"errcode":45009,"errmsg":"reach max api daily quota limit rid: 5f1e8a2b-0a1b2c3d"}`), "SetMenu")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expect *Error, got %v", err)
+	}
+	if apiErr.APIName != "SetMenu" || apiErr.ErrCode != 45009 || apiErr.RequestID != "5f1e8a2b-0a1b2c3d" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Error("45009 should be ErrQuotaExceeded")
+	}
+	if err := DecodeWithCommonError([]byte(`{"errcode":0,"errmsg":"ok"}`), "SetMenu"); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+}
+
+func TestDecodeWithPayError(t *testing.T) {
+	err := DecodeWithPayError([]byte(`<xml><return_code>SUCCESS</return_code><result_code>FAIL</result_code><err_code>SYSTEMERROR</err_code><err_code_des>system error</err_code_des></xml>`), "PrePayOrder")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expect *Error, got %v", err)
+	}
+	if apiErr.APIName != "PrePayOrder" || apiErr.PayErrCode != "SYSTEMERROR" || apiErr.ErrMsg != "system error" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+	if !errors.Is(err, ErrSystemBusy) {
+		t.Error("SYSTEMERROR should be ErrSystemBusy")
+	}
+	err = DecodeWithPayError([]byte(`<xml><return_code>FAIL</return_code><return_msg>签名错误</return_msg></xml>`), "Refund")
+	if !errors.As(err, &apiErr) || apiErr.PayErrCode != "FAIL" || apiErr.ErrMsg != "签名错误" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if errors.Is(err, ErrSystemBusy) {
+		t.Error("FAIL should not match any class")
+	}
+	if err := DecodeWithPayError([]byte(`<xml><return_code>SUCCESS</return_code><result_code>SUCCESS</result_code></xml>`), "Refund"); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 
 	"github.com/dcsunny/wechat/define"
-
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -100,7 +98,7 @@ func (d *Device) DeviceAuthorize(devices []ReqDevice, opType int, product string
 		ProductID:  product,
 	}
 	var response []byte
-	response, err = util.PostJSON(uri, req)
+	response, err = d.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +108,7 @@ func (d *Device) DeviceAuthorize(devices []ReqDevice, opType int, product string
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("DeviceAuthorize Error , errcode=%d , errmsg=%s", result.ErrCode, result.ErrMsg)
+		err = define.NewError("DeviceAuthorize", result.ErrCode, result.ErrMsg)
 		return
 	}
 	res = result.Resp
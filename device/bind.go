@@ -5,8 +5,6 @@ import (
 	"fmt"
 
 	"github.com/dcsunny/wechat/define"
-
-	"github.com/dcsunny/wechat/util"
 )
 
 // ReqBind 设备绑定解绑共通实体
@@ -27,7 +25,7 @@ func (d *Device) Bind(req ReqBind) (err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", uriBind, accessToken)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.PostJSON(uri, req); err != nil {
 		return
 	}
 	var result resBind
@@ -35,7 +33,7 @@ func (d *Device) Bind(req ReqBind) (err error) {
 		return
 	}
 	if result.BaseResp.ErrCode != 0 {
-		err = fmt.Errorf("DeviceBind Error , errcode=%d , errmsg=%s", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
+		err = define.NewError("DeviceBind", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
 		return
 	}
 	return
@@ -49,7 +47,7 @@ func (d *Device) Unbind(req ReqBind) (err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", uriUnbind, accessToken)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.PostJSON(uri, req); err != nil {
 		return
 	}
 	var result resBind
@@ -57,7 +55,7 @@ func (d *Device) Unbind(req ReqBind) (err error) {
 		return
 	}
 	if result.BaseResp.ErrCode != 0 {
-		err = fmt.Errorf("DeviceBind Error , errcode=%d , errmsg=%s", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
+		err = define.NewError("DeviceUnbind", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
 		return
 	}
 	return
@@ -71,7 +69,7 @@ func (d *Device) CompelBind(req ReqBind) (err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", uriCompelBind, accessToken)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.PostJSON(uri, req); err != nil {
 		return
 	}
 	var result resBind
@@ -79,7 +77,7 @@ func (d *Device) CompelBind(req ReqBind) (err error) {
 		return
 	}
 	if result.BaseResp.ErrCode != 0 {
-		err = fmt.Errorf("DeviceBind Error , errcode=%d , errmsg=%s", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
+		err = define.NewError("DeviceCompelBind", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
 		return
 	}
 	return
@@ -93,7 +91,7 @@ func (d *Device) CompelUnbind(req ReqBind) (err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", uriCompelUnbind, accessToken)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.PostJSON(uri, req); err != nil {
 		return
 	}
 	var result resBind
@@ -101,7 +99,7 @@ func (d *Device) CompelUnbind(req ReqBind) (err error) {
 		return
 	}
 	if result.BaseResp.ErrCode != 0 {
-		err = fmt.Errorf("DeviceBind Error , errcode=%d , errmsg=%s", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
+		err = define.NewError("DeviceCompelUnbind", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
 		return
 	}
 	return
@@ -1,13 +1,13 @@
 package device
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/dcsunny/wechat/define"
 
 	"github.com/dcsunny/wechat/context"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -33,6 +33,11 @@ func NewDevice(context *context.Context) *Device {
 	return device
 }
 
+//WithContext 返回绑定了ctx的Device，其发起的请求受ctx的取消和超时控制
+func (d *Device) WithContext(ctx stdcontext.Context) *Device {
+	return NewDevice(d.Context.WithContext(ctx))
+}
+
 // ResDeviceState 设备状态响应实体
 type ResDeviceState struct {
 	define.CommonError
@@ -48,14 +53,14 @@ func (d *Device) State(device string) (res ResDeviceState, err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s&device_id=%s", uriState, accessToken, device)
 	var response []byte
-	if response, err = util.HTTPGet(uri); err != nil {
+	if response, err = d.HTTPGet(uri); err != nil {
 		return
 	}
 	if err = json.Unmarshal(response, &res); err != nil {
 		return
 	}
 	if res.ErrCode != 0 {
-		err = fmt.Errorf("DeviceState Error , errcode=%d , errmsg=%s", res.ErrCode, res.ErrMsg)
+		err = define.NewError("DeviceState", res.ErrCode, res.ErrMsg)
 		return
 	}
 	return
@@ -5,8 +5,6 @@ import (
 	"fmt"
 
 	"github.com/dcsunny/wechat/define"
-
-	"github.com/dcsunny/wechat/util"
 )
 
 //ResCreateQRCode 获取二维码的返回实体
@@ -31,14 +29,14 @@ func (d *Device) CreateQRCode(devices []string) (res ResCreateQRCode, err error)
 		"device_id_list": devices,
 	}
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.PostJSON(uri, req); err != nil {
 		return
 	}
 	if err = json.Unmarshal(response, &res); err != nil {
 		return
 	}
 	if res.ErrCode != 0 {
-		err = fmt.Errorf("DeviceCreateQRCode Error , errcode=%d , errmsg=%s", res.ErrCode, res.ErrMsg)
+		err = define.NewError("DeviceCreateQRCode", res.ErrCode, res.ErrMsg)
 		return
 	}
 	return
@@ -64,14 +62,14 @@ func (d *Device) VerifyQRCode(ticket string) (res ResVerifyQRCode, err error) {
 	}
 	fmt.Println(req)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.PostJSON(uri, req); err != nil {
 		return
 	}
 	if err = json.Unmarshal(response, &res); err != nil {
 		return
 	}
 	if res.ErrCode != 0 {
-		err = fmt.Errorf("DeviceCreateQRCode Error , errcode=%d , errmsg=%s", res.ErrCode, res.ErrMsg)
+		err = define.NewError("DeviceVerifyQRCode", res.ErrCode, res.ErrMsg)
 		return
 	}
 	return
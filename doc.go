@@ -14,8 +14,8 @@ Package wechat provide wechat sdk for go
 	}
 	wc := wechat.NewWechat(config)
 
-	// 传入request和responseWriter
-	server := wc.GetServer(request, responseWriter)
+	// server实现了http.Handler，可并发处理所有请求
+	server := wc.NewServer()
 	server.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
 
 		//回复消息：演示回复用户发送的消息
@@ -23,8 +23,7 @@ Package wechat provide wechat sdk for go
 		return &message.Reply{message.MsgText, text}
 	})
 
-	server.Serve()
-	server.Send()
+	http.Handle("/wechat", server)
 
 
 更多信息：https://github.com/dcsunny/wechat
@@ -8,8 +8,7 @@ import (
 	"github.com/dcsunny/wechat/message"
 )
 
-func hello(rw http.ResponseWriter, req *http.Request) {
-
+func main() {
 	//配置微信参数
 	config := &wechat.Config{
 		AppID:          "your app id",
@@ -19,8 +18,8 @@ func hello(rw http.ResponseWriter, req *http.Request) {
 	}
 	wc := wechat.NewWechat(config)
 
-	// 传入request和responseWriter
-	server := wc.GetServer(req, rw)
+	//server可以并发处理所有请求
+	server := wc.NewServer()
 	//设置接收消息的处理方法
 	server.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
 
@@ -29,18 +28,7 @@ func hello(rw http.ResponseWriter, req *http.Request) {
 		return &message.Reply{MsgType: message.MsgTypeText, MsgData: text}
 	})
 
-	//处理消息接收以及回复
-	err := server.Serve()
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-	//发送回复的消息
-	server.Send()
-}
-
-func main() {
-	http.HandleFunc("/", hello)
+	http.Handle("/", server)
 	err := http.ListenAndServe(":8001", nil)
 	if err != nil {
 		fmt.Printf("start server error , err=%v", err)
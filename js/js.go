@@ -1,9 +1,9 @@
 package js
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/dcsunny/wechat/context"
 	"github.com/dcsunny/wechat/define"
@@ -40,6 +40,11 @@ func NewJs(context *context.Context) *Js {
 	return js
 }
 
+//WithContext 返回绑定了ctx的Js，其发起的请求受ctx的取消和超时控制
+func (js *Js) WithContext(ctx stdcontext.Context) *Js {
+	return NewJs(js.Context.WithContext(ctx))
+}
+
 //GetConfig 获取jssdk需要的配置参数
 //uri 为当前网页地址
 func (js *Js) GetConfig(uri string) (config *Config, err error) {
@@ -74,37 +79,71 @@ func (js *Js) GetTicket() (ticketStr string, err error) {
 		ticketStr = val.(string)
 		return
 	}
-	var ticket resTicket
-	ticket, err = js.getTicketFromServer()
+	return js.RefreshWithLock(fmt.Sprintf(define.JsAPITicketLockKey, js.AppID), func() string {
+		if val := js.Cache.Get(jsAPITicketCacheKey); val != nil {
+			ticketStr, _ := val.(string)
+			return ticketStr
+		}
+		return ""
+	}, func() (string, error) {
+		ticket, err := js.getTicketFromServer()
+		return ticket.Ticket, err
+	})
+}
+
+//RefreshTicket 在缓存过期前主动刷新jsapi_ticket，返回ticket及微信返回的有效期(秒)，用于后台刷新。
+//与GetTicket使用相同的锁，多实例同时刷新时只有一个实例请求微信，其他实例返回的expiresIn为0
+func (js *Js) RefreshTicket() (ticketStr string, expiresIn int64, err error) {
+	js.GetJsAPITicketLock().Lock()
+	defer js.GetJsAPITicketLock().Unlock()
+
+	jsAPITicketCacheKey := fmt.Sprintf("jsapi_ticket_%s", js.AppID)
+	staleTicket := js.Cache.GetString(jsAPITicketCacheKey)
+	ticketStr, err = js.RefreshWithLock(fmt.Sprintf(define.JsAPITicketLockKey, js.AppID), func() string {
+		if ticketStr := js.Cache.GetString(jsAPITicketCacheKey); ticketStr != staleTicket {
+			return ticketStr
+		}
+		return ""
+	}, func() (string, error) {
+		ticket, err := js.getTicketFromServer()
+		expiresIn = ticket.ExpiresIn
+		return ticket.Ticket, err
+	})
 	if err != nil {
-		return
+		return "", 0, err
 	}
-	ticketStr = ticket.Ticket
-	return
+	return ticketStr, expiresIn, nil
 }
 
 //getTicketFromServer 强制从服务器中获取ticket
 func (js *Js) getTicketFromServer() (ticket resTicket, err error) {
-	var accessToken string
-	accessToken, err = js.GetAccessToken()
-	if err != nil {
-		return
+	var url string
+	if js.JsAPITicketURL != "" {
+		url = fmt.Sprintf("%s%sappid=%s&type=jsapi", js.JsAPITicketURL, util.QuerySeparator(js.JsAPITicketURL), js.AppID)
+	} else {
+		var accessToken string
+		accessToken, err = js.GetAccessToken()
+		if err != nil {
+			return
+		}
+		url = fmt.Sprintf(getTicketURL, accessToken)
 	}
 
 	var response []byte
-	url := fmt.Sprintf(getTicketURL, accessToken)
-	response, err = util.HTTPGet(url)
+	response, err = js.HTTPGet(url)
+	if err != nil {
+		return
+	}
 	err = json.Unmarshal(response, &ticket)
 	if err != nil {
 		return
 	}
 	if ticket.ErrCode != 0 {
-		err = fmt.Errorf("getTicket Error : errcode=%d , errmsg=%s", ticket.ErrCode, ticket.ErrMsg)
+		err = define.NewError("GetTicket", ticket.ErrCode, ticket.ErrMsg)
 		return
 	}
 
 	jsAPITicketCacheKey := fmt.Sprintf("jsapi_ticket_%s", js.AppID)
-	expires := ticket.ExpiresIn - 1500
-	err = js.Cache.Set(jsAPITicketCacheKey, ticket.Ticket, time.Duration(expires)*time.Second)
+	err = js.Cache.Set(jsAPITicketCacheKey, ticket.Ticket, context.CacheExpires(ticket.ExpiresIn))
 	return
 }
@@ -1,6 +1,7 @@
 package material
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -31,6 +32,11 @@ func NewMaterial(context *context.Context) *Material {
 	return material
 }
 
+//WithContext 返回绑定了ctx的Material，其发起的请求受ctx的取消和超时控制
+func (material *Material) WithContext(ctx stdcontext.Context) *Material {
+	return NewMaterial(material.Context.WithContext(ctx))
+}
+
 //Article 永久图文素材
 type Article struct {
 	Title            string `json:"title"`
@@ -56,7 +62,7 @@ func (material *Material) GetNews(id string) ([]*Article, error) {
 		MediaID string `json:"media_id"`
 	}
 	req.MediaID = id
-	responseBytes, err := util.PostJSON(uri, req)
+	responseBytes, err := material.PostJSON(uri, req)
 
 	var res struct {
 		NewsItem []*Article `json:"news_item"`
@@ -92,7 +98,7 @@ func (material *Material) AddNews(articles []*Article) (mediaID string, err erro
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", addNewsURL, accessToken)
-	responseBytes, err := util.PostJSON(uri, req)
+	responseBytes, err := material.PostJSON(uri, req)
 	var res resArticles
 	err = json.Unmarshal(responseBytes, &res)
 	if err != nil {
@@ -123,7 +129,7 @@ func (material *Material) AddMaterial(mediaType MediaType, filename string) (med
 
 	uri := fmt.Sprintf("%s?access_token=%s&type=%s", addMaterialURL, accessToken, mediaType)
 	var response []byte
-	response, err = util.PostFile("media", filename, uri)
+	response, err = material.PostFile("media", filename, uri)
 	if err != nil {
 		return
 	}
@@ -133,7 +139,7 @@ func (material *Material) AddMaterial(mediaType MediaType, filename string) (med
 		return
 	}
 	if resMaterial.ErrCode != 0 {
-		err = fmt.Errorf("AddMaterial error : errcode=%v , errmsg=%v", resMaterial.ErrCode, resMaterial.ErrMsg)
+		err = define.NewError("AddMaterial", resMaterial.ErrCode, resMaterial.ErrMsg)
 		return
 	}
 	mediaID = resMaterial.MediaID
@@ -180,7 +186,7 @@ func (material *Material) AddVideo(filename, title, introduction string) (mediaI
 	}
 
 	var response []byte
-	response, err = util.PostMultipartForm(fields, uri)
+	response, err = material.PostMultipartForm(fields, uri)
 	if err != nil {
 		return
 	}
@@ -191,7 +197,7 @@ func (material *Material) AddVideo(filename, title, introduction string) (mediaI
 		return
 	}
 	if resMaterial.ErrCode != 0 {
-		err = fmt.Errorf("AddMaterial error : errcode=%v , errmsg=%v", resMaterial.ErrCode, resMaterial.ErrMsg)
+		err = define.NewError("AddVideo", resMaterial.ErrCode, resMaterial.ErrMsg)
 		return
 	}
 	mediaID = resMaterial.MediaID
@@ -211,7 +217,7 @@ func (material *Material) DeleteMaterial(mediaID string) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", delMaterialURL, accessToken)
-	response, err := util.PostJSON(uri, reqDeleteMaterial{mediaID})
+	response, err := material.PostJSON(uri, reqDeleteMaterial{mediaID})
 	if err != nil {
 		return err
 	}
@@ -6,7 +6,6 @@ import (
 	"io"
 
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 //MediaType 媒体文件类型
@@ -49,7 +48,7 @@ func (material *Material) MediaUpload(mediaType MediaType, filename string) (med
 
 	uri := fmt.Sprintf("%s?access_token=%s&type=%s", mediaUploadURL, accessToken, mediaType)
 	var response []byte
-	response, err = util.PostFile("media", filename, uri)
+	response, err = material.PostFile("media", filename, uri)
 	if err != nil {
 		return
 	}
@@ -58,7 +57,7 @@ func (material *Material) MediaUpload(mediaType MediaType, filename string) (med
 		return
 	}
 	if media.ErrCode != 0 {
-		err = fmt.Errorf("MediaUpload error : errcode=%v , errmsg=%v", media.ErrCode, media.ErrMsg)
+		err = define.NewError("MediaUpload", media.ErrCode, media.ErrMsg)
 		return
 	}
 	return
@@ -74,7 +73,7 @@ func (material *Material) MediaUploadV2(mediaType MediaType, filename string, fi
 
 	uri := fmt.Sprintf("%s?access_token=%s&type=%s", mediaUploadURL, accessToken, mediaType)
 	var response []byte
-	response, err = util.PostFileV2("media", filename, fileReader, uri)
+	response, err = material.PostFileV2("media", filename, fileReader, uri)
 	if err != nil {
 		return
 	}
@@ -83,7 +82,7 @@ func (material *Material) MediaUploadV2(mediaType MediaType, filename string, fi
 		return
 	}
 	if media.ErrCode != 0 {
-		err = fmt.Errorf("MediaUpload error : errcode=%v , errmsg=%v", media.ErrCode, media.ErrMsg)
+		err = define.NewError("MediaUploadV2", media.ErrCode, media.ErrMsg)
 		return
 	}
 	return
@@ -118,7 +117,7 @@ func (material *Material) ImageUpload(filename string) (url string, err error) {
 
 	uri := fmt.Sprintf("%s?access_token=%s", mediaUploadImageURL, accessToken)
 	var response []byte
-	response, err = util.PostFile("media", filename, uri)
+	response, err = material.PostFile("media", filename, uri)
 	if err != nil {
 		return
 	}
@@ -128,7 +127,7 @@ func (material *Material) ImageUpload(filename string) (url string, err error) {
 		return
 	}
 	if image.ErrCode != 0 {
-		err = fmt.Errorf("UploadImage error : errcode=%v , errmsg=%v", image.ErrCode, image.ErrMsg)
+		err = define.NewError("UploadImage", image.ErrCode, image.ErrMsg)
 		return
 	}
 	url = image.URL
@@ -1,6 +1,7 @@
 package menu
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
@@ -8,7 +9,6 @@ import (
 
 	"github.com/dcsunny/wechat/context"
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -121,6 +121,11 @@ func NewMenu(context *context.Context) *Menu {
 	return menu
 }
 
+//WithContext 返回绑定了ctx的Menu，其发起的请求受ctx的取消和超时控制
+func (menu *Menu) WithContext(ctx stdcontext.Context) *Menu {
+	return NewMenu(menu.Context.WithContext(ctx))
+}
+
 //SetMenu 设置按钮
 func (menu *Menu) SetMenu(buttons []*Button) error {
 	accessToken, err := menu.GetAccessToken()
@@ -133,7 +138,7 @@ func (menu *Menu) SetMenu(buttons []*Button) error {
 		Button: buttons,
 	}
 
-	response, err := util.PostJSON(uri, reqMenu)
+	response, err := menu.PostJSON(uri, reqMenu)
 	if err != nil {
 		return err
 	}
@@ -149,7 +154,7 @@ func (menu *Menu) GetMenu() (resMenu ResMenu, err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", menuGetURL, accessToken)
 	var response []byte
-	response, err = util.HTTPGet(uri)
+	response, err = menu.HTTPGet(uri)
 	if err != nil {
 		return
 	}
@@ -158,7 +163,7 @@ func (menu *Menu) GetMenu() (resMenu ResMenu, err error) {
 		return
 	}
 	if resMenu.ErrCode != 0 {
-		err = fmt.Errorf("GetMenu Error , errcode=%d , errmsg=%s", resMenu.ErrCode, resMenu.ErrMsg)
+		err = define.NewError("GetMenu", resMenu.ErrCode, resMenu.ErrMsg)
 		return
 	}
 	return
@@ -171,7 +176,7 @@ func (menu *Menu) DeleteMenu() error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", menuDeleteURL, accessToken)
-	response, err := util.HTTPGet(uri)
+	response, err := menu.HTTPGet(uri)
 	if err != nil {
 		return err
 	}
@@ -191,7 +196,7 @@ func (menu *Menu) AddConditional(buttons []*Button, matchRule *MatchRule) error
 		MatchRule: matchRule,
 	}
 
-	response, err := util.PostJSON(uri, reqMenu)
+	response, err := menu.PostJSON(uri, reqMenu)
 	if err != nil {
 		return err
 	}
@@ -210,7 +215,7 @@ func (menu *Menu) DeleteConditional(menuID int64) error {
 		MenuID: menuID,
 	}
 
-	response, err := util.PostJSON(uri, reqDeleteConditional)
+	response, err := menu.PostJSON(uri, reqDeleteConditional)
 	if err != nil {
 		return err
 	}
@@ -227,7 +232,7 @@ func (menu *Menu) MenuTryMatch(userID string) (buttons []Button, err error) {
 	uri := fmt.Sprintf("%s?access_token=%s", menuTryMatchURL, accessToken)
 	reqMenuTryMatch := &reqMenuTryMatch{userID}
 	var response []byte
-	response, err = util.PostJSON(uri, reqMenuTryMatch)
+	response, err = menu.PostJSON(uri, reqMenuTryMatch)
 	if err != nil {
 		return
 	}
@@ -237,7 +242,7 @@ func (menu *Menu) MenuTryMatch(userID string) (buttons []Button, err error) {
 		return
 	}
 	if resMenuTryMatch.ErrCode != 0 {
-		err = fmt.Errorf("MenuTryMatch Error , errcode=%d , errmsg=%s", resMenuTryMatch.ErrCode, resMenuTryMatch.ErrMsg)
+		err = define.NewError("MenuTryMatch", resMenuTryMatch.ErrCode, resMenuTryMatch.ErrMsg)
 		return
 	}
 	buttons = resMenuTryMatch.Button
@@ -253,7 +258,7 @@ func (menu *Menu) GetCurrentSelfMenuInfo() (resSelfMenuInfo ResSelfMenuInfo, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", menuSelfMenuInfoURL, accessToken)
 	var response []byte
-	response, err = util.HTTPGet(uri)
+	response, err = menu.HTTPGet(uri)
 	if err != nil {
 		return
 	}
@@ -263,7 +268,7 @@ func (menu *Menu) GetCurrentSelfMenuInfo() (resSelfMenuInfo ResSelfMenuInfo, err
 		return
 	}
 	if resSelfMenuInfo.ErrCode != 0 {
-		err = fmt.Errorf("GetCurrentSelfMenuInfo Error , errcode=%d , errmsg=%s", resSelfMenuInfo.ErrCode, resSelfMenuInfo.ErrMsg)
+		err = define.NewError("GetCurrentSelfMenuInfo", resSelfMenuInfo.ErrCode, resSelfMenuInfo.ErrMsg)
 		return
 	}
 	return
@@ -1,13 +1,13 @@
 package message
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/dcsunny/wechat/define"
 
 	"github.com/dcsunny/wechat/context"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -26,6 +26,11 @@ func NewMessageManager(context *context.Context) *Manager {
 	}
 }
 
+//WithContext 返回绑定了ctx的Manager，其发起的请求受ctx的取消和超时控制
+func (manager *Manager) WithContext(ctx stdcontext.Context) *Manager {
+	return NewMessageManager(manager.Context.WithContext(ctx))
+}
+
 //CustomerMessage  客服消息
 type CustomerMessage struct {
 	ToUser          string                `json:"touser"`                    //接受者OpenID
@@ -88,6 +93,55 @@ func NewCustomMiniprogrampageMessage(toUser string, title string, appID string,
 	}
 }
 
+//NewCustomerMessageFromReply 将被动回复的消息转换为客服消息，支持文本、图片、语音、视频、音乐、图文消息
+func NewCustomerMessageFromReply(toUser string, reply *Reply) (*CustomerMessage, error) {
+	if reply == nil || reply.MsgData == nil {
+		return nil, ErrInvalidReply
+	}
+	msg := &CustomerMessage{ToUser: toUser, Msgtype: reply.MsgType}
+	switch data := reply.MsgData.(type) {
+	case *Text:
+		msg.Msgtype = MsgTypeText
+		msg.Text = &MediaText{Content: string(data.Content)}
+	case *Image:
+		msg.Msgtype = MsgTypeImage
+		msg.Image = &MediaResource{MediaID: data.Image.MediaID}
+	case *Voice:
+		msg.Msgtype = MsgTypeVoice
+		msg.Voice = &MediaResource{MediaID: data.Voice.MediaID}
+	case *Video:
+		msg.Msgtype = MsgTypeVideo
+		msg.Video = &MediaVideo{
+			MediaID:     data.Video.MediaID,
+			Title:       data.Video.Title,
+			Description: data.Video.Description,
+		}
+	case *Music:
+		msg.Msgtype = MsgTypeMusic
+		msg.Music = &MediaMusic{
+			Title:        data.Music.Title,
+			Description:  data.Music.Description,
+			Musicurl:     data.Music.MusicURL,
+			Hqmusicurl:   data.Music.HQMusicURL,
+			ThumbMediaID: data.Music.ThumbMediaID,
+		}
+	case *News:
+		msg.Msgtype = MsgTypeNews
+		msg.News = &MediaNews{Articles: make([]MediaArticles, 0, len(data.Articles))}
+		for _, article := range data.Articles {
+			msg.News.Articles = append(msg.News.Articles, MediaArticles{
+				Title:       article.Title,
+				Description: article.Description,
+				URL:         article.URL,
+				Picurl:      article.PicURL,
+			})
+		}
+	default:
+		return nil, ErrUnsupportReply
+	}
+	return msg, nil
+}
+
 //MediaText 文本消息的文字
 type MediaText struct {
 	Content string `json:"content"`
@@ -161,14 +215,17 @@ func (manager *Manager) Send(msg *CustomerMessage) error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", customerSendMessage, accessToken)
-	response, err := util.PostJSON(uri, msg)
+	response, err := manager.PostJSON(uri, msg)
+	if err != nil {
+		return err
+	}
 	var result define.CommonError
 	err = json.Unmarshal(response, &result)
 	if err != nil {
 		return err
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("customer msg send error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("CustomerMessageSend", result.ErrCode, result.ErrMsg)
 		return err
 	}
 
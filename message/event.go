@@ -0,0 +1,281 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+//ErrEventMismatch 消息的事件类型与获取的事件结构不匹配
+var ErrEventMismatch = errors.New("事件类型不匹配")
+
+//MassSendJobFinishEvent 群发消息发送结果(MASSSENDJOBFINISH)
+type MassSendJobFinishEvent struct {
+	CommonToken
+	Event EventType `xml:"Event" json:"Event"`
+	//MsgID 群发的消息ID
+	MsgID int64 `xml:"MsgID" json:"MsgID"`
+	//Status 群发的结果，如send success、send fail、err(num)
+	Status      string `xml:"Status" json:"Status"`
+	TotalCount  int    `xml:"TotalCount" json:"TotalCount"`
+	FilterCount int    `xml:"FilterCount" json:"FilterCount"`
+	SentCount   int    `xml:"SentCount" json:"SentCount"`
+	ErrorCount  int    `xml:"ErrorCount" json:"ErrorCount"`
+	//CopyrightCheckResult 原创校验结果
+	CopyrightCheckResult CopyrightCheckResult `xml:"CopyrightCheckResult" json:"CopyrightCheckResult"`
+	//ArticleURLResult 群发成功的图文消息链接
+	ArticleURLResult ArticleURLResult `xml:"ArticleUrlResult" json:"ArticleUrlResult"`
+}
+
+//CopyrightCheckResult 群发图文的原创校验结果
+type CopyrightCheckResult struct {
+	Count      int                  `xml:"Count" json:"Count"`
+	ResultList []CopyrightCheckItem `xml:"ResultList>item" json:"ResultList"`
+	//CheckState 整体校验结果，1未被判为转载可以群发，2被判为转载可以群发，3被判为转载不能群发
+	CheckState int `xml:"CheckState" json:"CheckState"`
+}
+
+//CopyrightCheckItem 单篇文章的原创校验结果
+type CopyrightCheckItem struct {
+	//ArticleIdx 群发文章的序号，从1开始
+	ArticleIdx int `xml:"ArticleIdx" json:"ArticleIdx"`
+	//UserDeclareState 用户声明文章的状态
+	UserDeclareState int `xml:"UserDeclareState" json:"UserDeclareState"`
+	//AuditState 系统校验的状态
+	AuditState int `xml:"AuditState" json:"AuditState"`
+	//OriginalArticleURL 相似原创文的url
+	OriginalArticleURL string `xml:"OriginalArticleUrl" json:"OriginalArticleUrl"`
+	//OriginalArticleType 相似原创文的类型
+	OriginalArticleType int `xml:"OriginalArticleType" json:"OriginalArticleType"`
+	//CanReprint 是否能转载
+	CanReprint int `xml:"CanReprint" json:"CanReprint"`
+	//NeedReplaceContent 是否需要替换成原创文内容
+	NeedReplaceContent int `xml:"NeedReplaceContent" json:"NeedReplaceContent"`
+	//NeedShowReprintSource 是否需要注明转载来源
+	NeedShowReprintSource int `xml:"NeedShowReprintSource" json:"NeedShowReprintSource"`
+}
+
+//ArticleURLResult 群发成功的图文消息链接
+type ArticleURLResult struct {
+	Count      int              `xml:"Count" json:"Count"`
+	ResultList []ArticleURLItem `xml:"ResultList>item" json:"ResultList"`
+}
+
+//ArticleURLItem 单篇图文消息的链接
+type ArticleURLItem struct {
+	ArticleIdx int    `xml:"ArticleIdx" json:"ArticleIdx"`
+	ArticleURL string `xml:"ArticleUrl" json:"ArticleUrl"`
+}
+
+//SubscribeMsgEvent 订阅消息事件(subscribe_msg_popup_event、subscribe_msg_change_event、subscribe_msg_sent_event)
+type SubscribeMsgEvent struct {
+	CommonToken
+	Event EventType
+	List  []SubscribeMsgItem
+}
+
+//SubscribeMsgItem 单个模板的订阅结果或发送结果
+type SubscribeMsgItem struct {
+	TemplateID string `xml:"TemplateId" json:"TemplateId"`
+	//SubscribeStatusString 订阅结果，accept或reject，发送结果事件中为空
+	SubscribeStatusString string `xml:"SubscribeStatusString" json:"SubscribeStatusString"`
+	//PopupScene 弹框场景，0为h5页面，1为图文消息，2为支付后
+	PopupScene string `xml:"PopupScene" json:"PopupScene"`
+	//MsgID 发送结果事件中的消息ID
+	MsgID string `xml:"MsgID" json:"MsgID"`
+	//ErrorCode 发送结果事件中的推送结果状态码，0为成功
+	ErrorCode int `xml:"ErrorCode" json:"ErrorCode"`
+	//ErrorStatus 发送结果事件中的推送结果描述
+	ErrorStatus string `xml:"ErrorStatus" json:"ErrorStatus"`
+}
+
+//subscribeMsgList JSON格式中只有一项时List为对象
+type subscribeMsgList []SubscribeMsgItem
+
+func (l *subscribeMsgList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var item SubscribeMsgItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			return err
+		}
+		*l = subscribeMsgList{item}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]SubscribeMsgItem)(l))
+}
+
+//MediaCheckEvent 小程序音视频内容安全异步检测结果(wxa_media_check)
+type MediaCheckEvent struct {
+	CommonToken
+	Event   EventType `xml:"Event" json:"Event"`
+	AppID   string    `xml:"appid" json:"appid"`
+	TraceID string    `xml:"trace_id" json:"trace_id"`
+	//Version 检测接口的版本，2.0接口时为2
+	Version int `xml:"version" json:"version"`
+	//IsRisky 1.0接口的检测结果，1为有风险
+	IsRisky       int    `xml:"isrisky" json:"isrisky"`
+	ExtraInfoJSON string `xml:"extra_info_json" json:"extra_info_json"`
+	StatusCode    int    `xml:"status_code" json:"status_code"`
+	//Result 2.0接口的综合结果
+	Result MediaCheckResult `xml:"result" json:"result"`
+	//Detail 2.0接口的详细检测结果
+	Detail []MediaCheckDetail `xml:"detail" json:"detail"`
+}
+
+//MediaCheckResult 内容安全检测的综合结果
+type MediaCheckResult struct {
+	//Suggest 建议，risky、pass、review
+	Suggest string `xml:"suggest" json:"suggest"`
+	//Label 命中的标签
+	Label int `xml:"label" json:"label"`
+}
+
+//MediaCheckDetail 单个策略的检测结果
+type MediaCheckDetail struct {
+	Strategy string `xml:"strategy" json:"strategy"`
+	ErrCode  int    `xml:"errcode" json:"errcode"`
+	Suggest  string `xml:"suggest" json:"suggest"`
+	Label    int    `xml:"label" json:"label"`
+	Prob     int    `xml:"prob" json:"prob"`
+}
+
+//CardEvent 卡券事件(card_pass_check、card_not_pass_check、user_get_card、user_gifting_card、user_del_card、user_consume_card)
+type CardEvent struct {
+	CommonToken
+	Event  EventType `xml:"Event" json:"Event"`
+	CardID string    `xml:"CardId" json:"CardId"`
+	//RefuseReason 审核未通过的原因
+	RefuseReason string `xml:"RefuseReason" json:"RefuseReason"`
+	//IsGiveByFriend 是否为转赠领取，1为是
+	IsGiveByFriend  int    `xml:"IsGiveByFriend" json:"IsGiveByFriend"`
+	FriendUserName  string `xml:"FriendUserName" json:"FriendUserName"`
+	UserCardCode    string `xml:"UserCardCode" json:"UserCardCode"`
+	OldUserCardCode string `xml:"OldUserCardCode" json:"OldUserCardCode"`
+	//OuterID 领取场景值
+	OuterID  int    `xml:"OuterId" json:"OuterId"`
+	OuterStr string `xml:"OuterStr" json:"OuterStr"`
+	//IsRestoreMemberCard 是否为删除后重新领取的会员卡
+	IsRestoreMemberCard int    `xml:"IsRestoreMemberCard" json:"IsRestoreMemberCard"`
+	UnionID             string `xml:"UnionId" json:"UnionId"`
+	//IsReturnBack 转赠的卡券是否被退回
+	IsReturnBack int `xml:"IsReturnBack" json:"IsReturnBack"`
+	//IsChatRoom 是否转赠到群
+	IsChatRoom int `xml:"IsChatRoom" json:"IsChatRoom"`
+	//ConsumeSource 核销来源，如FROM_API、FROM_MOBILE_HELPER
+	ConsumeSource string `xml:"ConsumeSource" json:"ConsumeSource"`
+	LocationName  string `xml:"LocationName" json:"LocationName"`
+	LocationID    int64  `xml:"LocationId" json:"LocationId"`
+	StaffOpenID   string `xml:"StaffOpenId" json:"StaffOpenId"`
+	VerifyCode    string `xml:"VerifyCode" json:"VerifyCode"`
+	RemarkAmount  string `xml:"RemarkAmount" json:"RemarkAmount"`
+}
+
+//ViewMiniprogramEvent 点击菜单跳转小程序(view_miniprogram)
+type ViewMiniprogramEvent struct {
+	CommonToken
+	Event EventType `xml:"Event" json:"Event"`
+	//PagePath 跳转的小程序路径
+	PagePath string `xml:"EventKey" json:"EventKey"`
+	MenuID   string `xml:"MenuId" json:"MenuId"`
+}
+
+//GuideScanEvent 用户扫描导购顾问二维码(guide_qrcode_scan_event)
+type GuideScanEvent struct {
+	CommonToken
+	Event        EventType `xml:"Event" json:"Event"`
+	GuideAccount string    `xml:"GuideScanEvent>GuideAccount" json:"GuideAccount"`
+	GuideOpenID  string    `xml:"GuideScanEvent>GuideOpenid" json:"GuideOpenid"`
+	QrcodeInfo   string    `xml:"GuideScanEvent>QrcodeInfo" json:"QrcodeInfo"`
+	Action       int       `xml:"GuideScanEvent>Action" json:"Action"`
+}
+
+//MassSendJobFinish 获取群发消息发送结果事件
+func (msg MixMessage) MassSendJobFinish() (*MassSendJobFinishEvent, error) {
+	event := new(MassSendJobFinishEvent)
+	if err := msg.decodeEvent(event, EventMassSendJobFinish); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+//SubscribeMsg 获取订阅消息弹框、订阅设置变更或发送结果事件
+func (msg MixMessage) SubscribeMsg() (*SubscribeMsgEvent, error) {
+	var raw struct {
+		CommonToken
+		Event  EventType          `xml:"Event" json:"Event"`
+		Popup  []SubscribeMsgItem `xml:"SubscribeMsgPopupEvent>List" json:"-"`
+		Change []SubscribeMsgItem `xml:"SubscribeMsgChangeEvent>List" json:"-"`
+		Sent   []SubscribeMsgItem `xml:"SubscribeMsgSentEvent>List" json:"-"`
+		List   subscribeMsgList   `xml:"-" json:"List"`
+	}
+	if err := msg.decodeEvent(&raw, EventSubscribeMsgPopup, EventSubscribeMsgChange, EventSubscribeMsgSent); err != nil {
+		return nil, err
+	}
+	event := &SubscribeMsgEvent{CommonToken: raw.CommonToken, Event: raw.Event, List: raw.List}
+	for _, list := range [][]SubscribeMsgItem{raw.Popup, raw.Change, raw.Sent} {
+		event.List = append(event.List, list...)
+	}
+	return event, nil
+}
+
+//MediaCheck 获取小程序音视频内容安全异步检测结果事件
+func (msg MixMessage) MediaCheck() (*MediaCheckEvent, error) {
+	event := new(MediaCheckEvent)
+	if err := msg.decodeEvent(event, EventWxaMediaCheck); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+//Card 获取卡券审核、领取、转赠、删除、核销事件
+func (msg MixMessage) Card() (*CardEvent, error) {
+	event := new(CardEvent)
+	if err := msg.decodeEvent(event, EventCardPassCheck, EventCardNotPassCheck, EventUserGetCard,
+		EventUserGiftingCard, EventUserDelCard, EventUserConsumeCard); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+//ViewMiniprogram 获取点击菜单跳转小程序事件
+func (msg MixMessage) ViewMiniprogram() (*ViewMiniprogramEvent, error) {
+	event := new(ViewMiniprogramEvent)
+	if err := msg.decodeEvent(event, EventViewMiniprogram); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+//GuideScan 获取扫描导购顾问二维码事件
+func (msg MixMessage) GuideScan() (*GuideScanEvent, error) {
+	event := new(GuideScanEvent)
+	if err := msg.decodeEvent(event, EventGuideQRCodeScan); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+//decodeEvent 校验事件类型后将原始消息解析到v中，原始消息可以是XML或JSON格式
+func (msg MixMessage) decodeEvent(v interface{}, events ...EventType) error {
+	matched := false
+	for _, event := range events {
+		if msg.Event == event {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return fmt.Errorf("%w: %s", ErrEventMismatch, msg.Event)
+	}
+	raw := bytes.TrimSpace(msg.Raw)
+	if len(raw) == 0 {
+		return fmt.Errorf("缺少原始消息内容")
+	}
+	if raw[0] == '{' {
+		return json.Unmarshal(raw, v)
+	}
+	return xml.Unmarshal(raw, v)
+}
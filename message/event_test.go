@@ -0,0 +1,62 @@
+package message
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+)
+
+func parseXML(t *testing.T, raw string) MixMessage {
+	var msg MixMessage
+	if err := xml.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.Raw = []byte(raw)
+	return msg
+}
+
+func TestMixMessage_Events(t *testing.T) {
+	msg := parseXML(t, `<xml><ToUserName><![CDATA[gh_test]]></ToUserName><FromUserName><![CDATA[openid]]></FromUserName><CreateTime>1600000000</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[MASSSENDJOBFINISH]]></Event><MsgID>1000001625</MsgID><Status><![CDATA[err(30003)]]></Status><TotalCount>10</TotalCount><FilterCount>8</FilterCount><SentCount>8</SentCount><ErrorCount>0</ErrorCount><CopyrightCheckResult><Count>1</Count><ResultList><item><ArticleIdx>1</ArticleIdx><UserDeclareState>0</UserDeclareState><AuditState>2</AuditState><OriginalArticleUrl><![CDATA[Url_1]]></OriginalArticleUrl><OriginalArticleType>1</OriginalArticleType><CanReprint>1</CanReprint><NeedReplaceContent>1</NeedReplaceContent><NeedShowReprintSource>1</NeedShowReprintSource></item></ResultList><CheckState>2</CheckState></CopyrightCheckResult></xml>`)
+	mass, err := msg.MassSendJobFinish()
+	if err != nil || mass.MsgID != 1000001625 || mass.SentCount != 8 || mass.CopyrightCheckResult.CheckState != 2 ||
+		len(mass.CopyrightCheckResult.ResultList) != 1 || mass.CopyrightCheckResult.ResultList[0].OriginalArticleURL != "Url_1" {
+		t.Errorf("unexpected MASSSENDJOBFINISH %+v %v", mass, err)
+	}
+	if _, err := msg.Card(); !errors.Is(err, ErrEventMismatch) {
+		t.Errorf("expect ErrEventMismatch, got %v", err)
+	}
+
+	msg = parseXML(t, `<xml><ToUserName><![CDATA[gh_test]]></ToUserName><FromUserName><![CDATA[openid]]></FromUserName><CreateTime>1600000000</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[subscribe_msg_popup_event]]></Event><SubscribeMsgPopupEvent><List><TemplateId><![CDATA[tpl1]]></TemplateId><SubscribeStatusString><![CDATA[accept]]></SubscribeStatusString><PopupScene>2</PopupScene></List><List><TemplateId><![CDATA[tpl2]]></TemplateId><SubscribeStatusString><![CDATA[reject]]></SubscribeStatusString><PopupScene>2</PopupScene></List></SubscribeMsgPopupEvent></xml>`)
+	popup, err := msg.SubscribeMsg()
+	if err != nil || len(popup.List) != 2 || popup.List[1].TemplateID != "tpl2" || popup.List[1].SubscribeStatusString != "reject" {
+		t.Errorf("unexpected subscribe_msg_popup_event %+v %v", popup, err)
+	}
+
+	raw := `{"ToUserName":"gh_test","FromUserName":"openid","CreateTime":1600000000,"MsgType":"event","Event":"subscribe_msg_sent_event","List":{"TemplateId":"tpl1","MsgID":"1700827132819554304","ErrorCode":0,"ErrorStatus":"success"}}`
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.Raw = []byte(raw)
+	sent, err := msg.SubscribeMsg()
+	if err != nil || len(sent.List) != 1 || sent.List[0].MsgID != "1700827132819554304" || sent.List[0].ErrorStatus != "success" {
+		t.Errorf("unexpected subscribe_msg_sent_event %+v %v", sent, err)
+	}
+
+	raw = `{"ToUserName":"gh_test","FromUserName":"openid","CreateTime":1600000000,"MsgType":"event","Event":"wxa_media_check","appid":"wxappid","trace_id":"trace","version":2,"result":{"suggest":"risky","label":20002},"detail":[{"strategy":"content_model","errcode":0,"suggest":"risky","label":20002,"prob":90}]}`
+	msg = MixMessage{}
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.Raw = []byte(raw)
+	check, err := msg.MediaCheck()
+	if err != nil || check.TraceID != "trace" || check.Result.Suggest != "risky" || len(check.Detail) != 1 || check.Detail[0].Prob != 90 {
+		t.Errorf("unexpected wxa_media_check %+v %v", check, err)
+	}
+
+	msg = parseXML(t, `<xml><ToUserName><![CDATA[gh_test]]></ToUserName><FromUserName><![CDATA[openid]]></FromUserName><CreateTime>1600000000</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[user_consume_card]]></Event><CardId><![CDATA[card]]></CardId><UserCardCode><![CDATA[12312312]]></UserCardCode><ConsumeSource><![CDATA[FROM_API]]></ConsumeSource><LocationId>1</LocationId></xml>`)
+	card, err := msg.Card()
+	if err != nil || card.CardID != "card" || card.UserCardCode != "12312312" || card.ConsumeSource != "FROM_API" || card.LocationID != 1 {
+		t.Errorf("unexpected user_consume_card %+v %v", card, err)
+	}
+}
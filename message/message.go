@@ -1,7 +1,9 @@
 package message
 
 import (
+	"encoding/json"
 	"encoding/xml"
+	"strings"
 
 	"github.com/dcsunny/wechat/device"
 )
@@ -73,6 +75,32 @@ const (
 	EventTemplateSendJobFinish = "TEMPLATESENDJOBFINISH"
 	//EventUserEnterTempsession 用户在小程序“客服会话按钮”进入客服会话时
 	EventUserEnterTempsession = "user_enter_tempsession"
+	//EventMassSendJobFinish 群发消息发送结果，含原创校验结果
+	EventMassSendJobFinish = "MASSSENDJOBFINISH"
+	//EventSubscribeMsgPopup 用户在订阅消息弹框中操作
+	EventSubscribeMsgPopup = "subscribe_msg_popup_event"
+	//EventSubscribeMsgChange 用户在服务通知管理页面修改订阅消息设置
+	EventSubscribeMsgChange = "subscribe_msg_change_event"
+	//EventSubscribeMsgSent 发送订阅通知的结果
+	EventSubscribeMsgSent = "subscribe_msg_sent_event"
+	//EventWxaMediaCheck 小程序音视频内容安全异步检测结果
+	EventWxaMediaCheck = "wxa_media_check"
+	//EventCardPassCheck 卡券审核通过
+	EventCardPassCheck = "card_pass_check"
+	//EventCardNotPassCheck 卡券审核未通过
+	EventCardNotPassCheck = "card_not_pass_check"
+	//EventUserGetCard 用户领取卡券
+	EventUserGetCard = "user_get_card"
+	//EventUserGiftingCard 用户转赠卡券
+	EventUserGiftingCard = "user_gifting_card"
+	//EventUserDelCard 用户删除卡券
+	EventUserDelCard = "user_del_card"
+	//EventUserConsumeCard 卡券被核销
+	EventUserConsumeCard = "user_consume_card"
+	//EventViewMiniprogram 点击菜单跳转小程序
+	EventViewMiniprogram = "view_miniprogram"
+	//EventGuideQRCodeScan 用户扫描导购顾问二维码
+	EventGuideQRCodeScan = "guide_qrcode_scan_event"
 )
 
 const (
@@ -91,88 +119,110 @@ type MixMessage struct {
 	CommonToken
 
 	//基本消息
-	MsgID        int64   `xml:"MsgId"`
-	Content      string  `xml:"Content"`
-	Recognition  string  `xml:"Recognition"`
-	PicURL       string  `xml:"PicUrl"`
-	MediaID      string  `xml:"MediaId"`
-	Format       string  `xml:"Format"`
-	ThumbMediaID string  `xml:"ThumbMediaId"`
-	LocationX    float64 `xml:"Location_X"`
-	LocationY    float64 `xml:"Location_Y"`
-	Scale        float64 `xml:"Scale"`
-	Label        string  `xml:"Label"`
-	Title        string  `xml:"Title"` //小程序消息也有
-	Description  string  `xml:"Description"`
-	URL          string  `xml:"Url"`
-	Bizmsgmenuid string  `xml:"bizmsgmenuid"`
+	MsgID        int64   `xml:"MsgId" json:"MsgId"`
+	Content      string  `xml:"Content" json:"Content"`
+	Recognition  string  `xml:"Recognition" json:"Recognition"`
+	PicURL       string  `xml:"PicUrl" json:"PicUrl"`
+	MediaID      string  `xml:"MediaId" json:"MediaId"`
+	Format       string  `xml:"Format" json:"Format"`
+	ThumbMediaID string  `xml:"ThumbMediaId" json:"ThumbMediaId"`
+	LocationX    float64 `xml:"Location_X" json:"Location_X"`
+	LocationY    float64 `xml:"Location_Y" json:"Location_Y"`
+	Scale        float64 `xml:"Scale" json:"Scale"`
+	Label        string  `xml:"Label" json:"Label"`
+	Title        string  `xml:"Title" json:"Title"` //小程序消息也有
+	Description  string  `xml:"Description" json:"Description"`
+	URL          string  `xml:"Url" json:"Url"`
+	Bizmsgmenuid string  `xml:"bizmsgmenuid" json:"bizmsgmenuid"`
 
 	//事件相关
-	Event       EventType `xml:"Event"`
-	EventKey    string    `xml:"EventKey"`
-	Ticket      string    `xml:"Ticket"`
-	Latitude    string    `xml:"Latitude"`
-	Longitude   string    `xml:"Longitude"`
-	Precision   string    `xml:"Precision"`
-	MenuID      string    `xml:"MenuId"`
-	Status      string    `xml:"Status"`
-	SessionFrom string    `xml:"SessionFrom"` //小程序消息也有
+	Event       EventType `xml:"Event" json:"Event"`
+	EventKey    string    `xml:"EventKey" json:"EventKey"`
+	Ticket      string    `xml:"Ticket" json:"Ticket"`
+	Latitude    string    `xml:"Latitude" json:"Latitude"`
+	Longitude   string    `xml:"Longitude" json:"Longitude"`
+	Precision   string    `xml:"Precision" json:"Precision"`
+	MenuID      string    `xml:"MenuId" json:"MenuId"`
+	Status      string    `xml:"Status" json:"Status"`
+	SessionFrom string    `xml:"SessionFrom" json:"SessionFrom"` //小程序消息也有
 
 	ScanCodeInfo struct {
-		ScanType   string `xml:"ScanType"`
-		ScanResult string `xml:"ScanResult"`
-	} `xml:"ScanCodeInfo"`
+		ScanType   string `xml:"ScanType" json:"ScanType"`
+		ScanResult string `xml:"ScanResult" json:"ScanResult"`
+	} `xml:"ScanCodeInfo" json:"ScanCodeInfo"`
 
 	SendPicsInfo struct {
-		Count   int32      `xml:"Count"`
-		PicList []EventPic `xml:"PicList>item"`
-	} `xml:"SendPicsInfo"`
+		Count   int32      `xml:"Count" json:"Count"`
+		PicList []EventPic `xml:"PicList>item" json:"PicList"`
+	} `xml:"SendPicsInfo" json:"SendPicsInfo"`
 
 	SendLocationInfo struct {
-		LocationX float64 `xml:"Location_X"`
-		LocationY float64 `xml:"Location_Y"`
-		Scale     float64 `xml:"Scale"`
-		Label     string  `xml:"Label"`
-		Poiname   string  `xml:"Poiname"`
-	}
+		LocationX float64 `xml:"Location_X" json:"Location_X"`
+		LocationY float64 `xml:"Location_Y" json:"Location_Y"`
+		Scale     float64 `xml:"Scale" json:"Scale"`
+		Label     string  `xml:"Label" json:"Label"`
+		Poiname   string  `xml:"Poiname" json:"Poiname"`
+	} `xml:"SendLocationInfo" json:"SendLocationInfo"`
 
 	// 第三方平台相关
-	InfoType                     InfoType `xml:"InfoType"`
-	AppID                        string   `xml:"AppId"` //小程序消息也有
-	ComponentVerifyTicket        string   `xml:"ComponentVerifyTicket"`
-	AuthorizerAppid              string   `xml:"AuthorizerAppid"`
-	AuthorizationCode            string   `xml:"AuthorizationCode"`
-	AuthorizationCodeExpiredTime int64    `xml:"AuthorizationCodeExpiredTime"`
-	PreAuthCode                  string   `xml:"PreAuthCode"`
+	InfoType                     InfoType `xml:"InfoType" json:"InfoType"`
+	AppID                        string   `xml:"AppId" json:"AppId"` //小程序消息也有
+	ComponentVerifyTicket        string   `xml:"ComponentVerifyTicket" json:"ComponentVerifyTicket"`
+	AuthorizerAppid              string   `xml:"AuthorizerAppid" json:"AuthorizerAppid"`
+	AuthorizationCode            string   `xml:"AuthorizationCode" json:"AuthorizationCode"`
+	AuthorizationCodeExpiredTime int64    `xml:"AuthorizationCodeExpiredTime" json:"AuthorizationCodeExpiredTime"`
+	PreAuthCode                  string   `xml:"PreAuthCode" json:"PreAuthCode"`
 
 	// 卡券相关
-	CardID              string `xml:"CardId"`
-	RefuseReason        string `xml:"RefuseReason"`
-	IsGiveByFriend      int32  `xml:"IsGiveByFriend"`
-	FriendUserName      string `xml:"FriendUserName"`
-	UserCardCode        string `xml:"UserCardCode"`
-	OldUserCardCode     string `xml:"OldUserCardCode"`
-	OuterStr            string `xml:"OuterStr"`
-	IsRestoreMemberCard int32  `xml:"IsRestoreMemberCard"`
-	UnionID             string `xml:"UnionId"`
+	CardID              string `xml:"CardId" json:"CardId"`
+	RefuseReason        string `xml:"RefuseReason" json:"RefuseReason"`
+	IsGiveByFriend      int32  `xml:"IsGiveByFriend" json:"IsGiveByFriend"`
+	FriendUserName      string `xml:"FriendUserName" json:"FriendUserName"`
+	UserCardCode        string `xml:"UserCardCode" json:"UserCardCode"`
+	OldUserCardCode     string `xml:"OldUserCardCode" json:"OldUserCardCode"`
+	OuterStr            string `xml:"OuterStr" json:"OuterStr"`
+	IsRestoreMemberCard int32  `xml:"IsRestoreMemberCard" json:"IsRestoreMemberCard"`
+	UnionID             string `xml:"UnionId" json:"UnionId"`
 
 	// 内容审核相关
-	IsRisky       bool   `xml:"isrisky"`
-	ExtraInfoJSON string `xml:"extra_info_json"`
-	TraceID       string `xml:"trace_id"`
-	StatusCode    int    `xml:"status_code"`
+	IsRisky       bool   `xml:"isrisky" json:"-"` //JSON格式中为0/1
+	ExtraInfoJSON string `xml:"extra_info_json" json:"extra_info_json"`
+	TraceID       string `xml:"trace_id" json:"trace_id"`
+	StatusCode    int    `xml:"status_code" json:"status_code"`
 
 	//设备相关
 	device.MsgDevice
 
 	//小程序相关
-	PagePath string `xml:"PagePath"`
-	ThumbUrl string `xml:"ThumbUrl"`
+	PagePath string `xml:"PagePath" json:"PagePath"`
+	ThumbUrl string `xml:"ThumbUrl" json:"ThumbUrl"`
+
+	//Raw 解密后的原始消息内容
+	Raw []byte `xml:"-" json:"-"`
+}
+
+//UnmarshalJSON 解析JSON格式的消息，小程序消息推送可配置为JSON格式
+func (msg *MixMessage) UnmarshalJSON(data []byte) error {
+	type mixMessage MixMessage
+	aux := struct {
+		*mixMessage
+		IsRisky json.RawMessage `json:"isrisky"`
+	}{mixMessage: (*mixMessage)(msg)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	switch strings.Trim(string(aux.IsRisky), `"`) {
+	case "1", "true":
+		msg.IsRisky = true
+	default:
+		msg.IsRisky = false
+	}
+	return nil
 }
 
 //EventPic 发图事件推送
 type EventPic struct {
-	PicMd5Sum string `xml:"PicMd5Sum"`
+	PicMd5Sum string `xml:"PicMd5Sum" json:"PicMd5Sum"`
 }
 
 //EncryptedXMLMsg 安全模式下的消息体
@@ -207,11 +257,11 @@ func (c CDATA) String() string {
 
 // CommonToken 消息中通用的结构
 type CommonToken struct {
-	XMLName      xml.Name `xml:"xml"`
-	ToUserName   CDATA    `xml:"ToUserName"`
-	FromUserName CDATA    `xml:"FromUserName"`
-	CreateTime   int64    `xml:"CreateTime"`
-	MsgType      MsgType  `xml:"MsgType"`
+	XMLName      xml.Name `xml:"xml" json:"-"`
+	ToUserName   CDATA    `xml:"ToUserName" json:"ToUserName"`
+	FromUserName CDATA    `xml:"FromUserName" json:"FromUserName"`
+	CreateTime   int64    `xml:"CreateTime" json:"CreateTime"`
+	MsgType      MsgType  `xml:"MsgType" json:"MsgType"`
 }
 
 //SetToUserName set ToUserName
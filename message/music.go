@@ -5,12 +5,12 @@ type Music struct {
 	CommonToken
 
 	Music struct {
-		Title        string `xml:"Title"        `
-		Description  string `xml:"Description"  `
-		MusicURL     string `xml:"MusicUrl"     `
-		HQMusicURL   string `xml:"HQMusicUrl"   `
-		ThumbMediaID string `xml:"ThumbMediaId"`
-	} `xml:"Music"`
+		Title        string `xml:"Title" json:"Title"`
+		Description  string `xml:"Description" json:"Description"`
+		MusicURL     string `xml:"MusicUrl" json:"MusicUrl"`
+		HQMusicURL   string `xml:"HQMusicUrl" json:"HQMusicUrl"`
+		ThumbMediaID string `xml:"ThumbMediaId" json:"ThumbMediaId"`
+	} `xml:"Music" json:"Music"`
 }
 
 //NewMusic  回复音乐消息
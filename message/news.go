@@ -4,8 +4,8 @@ package message
 type News struct {
 	CommonToken
 
-	ArticleCount int        `xml:"ArticleCount"`
-	Articles     []*Article `xml:"Articles>item,omitempty"`
+	ArticleCount int        `xml:"ArticleCount" json:"ArticleCount"`
+	Articles     []*Article `xml:"Articles>item,omitempty" json:"Articles,omitempty"`
 }
 
 //NewNews 初始化图文消息
@@ -18,10 +18,10 @@ func NewNews(articles []*Article) *News {
 
 //Article 单篇文章
 type Article struct {
-	Title       string `xml:"Title,omitempty"`
-	Description string `xml:"Description,omitempty"`
-	PicURL      string `xml:"PicUrl,omitempty"`
-	URL         string `xml:"Url,omitempty"`
+	Title       string `xml:"Title,omitempty" json:"Title,omitempty"`
+	Description string `xml:"Description,omitempty" json:"Description,omitempty"`
+	PicURL      string `xml:"PicUrl,omitempty" json:"PicUrl,omitempty"`
+	URL         string `xml:"Url,omitempty" json:"Url,omitempty"`
 }
 
 //NewArticle 初始化文章
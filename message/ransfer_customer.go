@@ -4,12 +4,12 @@ package message
 type TransferCustomer struct {
 	CommonToken
 
-	TransInfo *TransInfo `xml:"TransInfo,omitempty"`
+	TransInfo *TransInfo `xml:"TransInfo,omitempty" json:"TransInfo,omitempty"`
 }
 
 //TransInfo 转发到指定客服
 type TransInfo struct {
-	KfAccount string `xml:"KfAccount"`
+	KfAccount string `xml:"KfAccount" json:"KfAccount"`
 }
 
 //NewTransferCustomer 实例化
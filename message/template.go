@@ -1,6 +1,7 @@
 package message
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
@@ -8,7 +9,6 @@ import (
 
 	"github.com/dcsunny/wechat/context"
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -31,6 +31,11 @@ func NewTemplate(context *context.Context) *Template {
 	return tpl
 }
 
+//WithContext 返回绑定了ctx的Template，其发起的请求受ctx的取消和超时控制
+func (tpl *Template) WithContext(ctx stdcontext.Context) *Template {
+	return NewTemplate(tpl.Context.WithContext(ctx))
+}
+
 //Message 发送的模板消息内容
 type Message struct {
 	ToUser      string               `json:"touser"`          // 必须, 接受者OpenID
@@ -68,7 +73,7 @@ func (tpl *Template) Send(msg *Message) (msgID int64, err error) {
 		return
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", templateSendURL, accessToken)
-	response, err := util.PostJSON(uri, msg)
+	response, err := tpl.PostJSON(uri, msg)
 
 	var result resTemplateSend
 	err = json.Unmarshal(response, &result)
@@ -107,7 +112,7 @@ func (tpl *Template) SendMiniOrMp(msg *MiniMpMessage) (err error) {
 		return
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", templateMiniOrMpSendURL, accessToken)
-	response, err := util.PostJSON(uri, msg)
+	response, err := tpl.PostJSON(uri, msg)
 
 	var result resTemplateMiniSend
 	err = json.Unmarshal(response, &result)
@@ -135,7 +140,7 @@ func (tpl *Template) SendSubscribeMessage(msg *SubscribeMessage) (err error) {
 		return
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", templateSubscribeSendURL, accessToken)
-	response, err := util.PostJSON(uri, msg)
+	response, err := tpl.PostJSON(uri, msg)
 	var result define.CommonError
 	err = json.Unmarshal(response, &result)
 	if err != nil {
@@ -164,7 +169,7 @@ func (tpl *Template) SendMiniSubscribeMessage(msg *MiniSubscribeMessage) (err er
 		return
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", templateMiniSubscribeSendURL, accessToken)
-	response, err := util.PostJSON(uri, msg)
+	response, err := tpl.PostJSON(uri, msg)
 	var result define.CommonError
 	err = json.Unmarshal(response, &result)
 	if err != nil {
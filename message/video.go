@@ -5,10 +5,10 @@ type Video struct {
 	CommonToken
 
 	Video struct {
-		MediaID     string `xml:"MediaId"`
-		Title       string `xml:"Title,omitempty"`
-		Description string `xml:"Description,omitempty"`
-	} `xml:"Video"`
+		MediaID     string `xml:"MediaId" json:"MediaId"`
+		Title       string `xml:"Title,omitempty" json:"Title,omitempty"`
+		Description string `xml:"Description,omitempty" json:"Description,omitempty"`
+	} `xml:"Video" json:"Video"`
 }
 
 //NewVideo 回复图片消息
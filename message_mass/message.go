@@ -1,12 +1,12 @@
 package message_mass
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/dcsunny/wechat/context"
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -24,6 +24,11 @@ func NewMessageMass(context *context.Context) *MessageMass {
 	return service
 }
 
+//WithContext 返回绑定了ctx的MessageMass，其发起的请求受ctx的取消和超时控制
+func (service *MessageMass) WithContext(ctx stdcontext.Context) *MessageMass {
+	return NewMessageMass(service.Context.WithContext(ctx))
+}
+
 type MessageByOpen struct {
 	Touser  []string `json:"touser"`
 	Msgtype string   `json:"msgtype"`
@@ -75,14 +80,14 @@ func (service *MessageMass) Send(msg *MessageByOpen) (result MessageSassResult,
 		return
 	}
 	uri := fmt.Sprintf(MessageMassSendByOpenIdURL, accessToken)
-	response, err := util.PostJSON(uri, msg)
+	response, err := service.PostJSON(uri, msg)
 
 	err = json.Unmarshal(response, &result)
 	if err != nil {
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("message mass send error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("MessageMassSend", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -105,7 +110,7 @@ func (service *MessageMass) SendByTag(msg *MessageByTag) (result MessageSassResu
 		return
 	}
 	uri := fmt.Sprintf(MessageMassSendByTagURL, accessToken)
-	response, err := util.PostJSON(uri, msg)
+	response, err := service.PostJSON(uri, msg)
 
 	err = json.Unmarshal(response, &result)
 	if err != nil {
@@ -113,7 +118,7 @@ func (service *MessageMass) SendByTag(msg *MessageByTag) (result MessageSassResu
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("message mass send error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("MessageMassSendByTag", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
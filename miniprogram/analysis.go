@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -39,7 +38,7 @@ func (wxa *MiniProgram) fetchData(urlStr string, body interface{}) (response []b
 		return
 	}
 	urlStr = fmt.Sprintf(urlStr, accessToken)
-	response, err = util.PostJSON(urlStr, body)
+	response, err = wxa.PostJSON(urlStr, body)
 	return
 }
 
@@ -72,7 +71,7 @@ func (wxa *MiniProgram) getAnalysisRetain(urlStr string, beginDate, endDate stri
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("getAnalysisRetain error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("getAnalysisRetain", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -120,7 +119,7 @@ func (wxa *MiniProgram) GetAnalysisDailySummary(beginDate, endDate string) (resu
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetAnalysisDailySummary error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("GetAnalysisDailySummary", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -156,7 +155,7 @@ func (wxa *MiniProgram) getAnalysisVisitTrend(urlStr string, beginDate, endDate
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("getAnalysisVisitTrend error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("getAnalysisVisitTrend", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -218,7 +217,7 @@ func (wxa *MiniProgram) GetAnalysisUserPortrait(beginDate, endDate string) (resu
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetAnalysisUserPortrait error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("GetAnalysisUserPortrait", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -259,7 +258,7 @@ func (wxa *MiniProgram) GetAnalysisVisitDistribution(beginDate, endDate string)
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetAnalysisVisitDistribution error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("GetAnalysisVisitDistribution", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -299,7 +298,7 @@ func (wxa *MiniProgram) GetAnalysisVisitPage(beginDate, endDate string) (result
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetAnalysisVisitPage error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("GetAnalysisVisitPage", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
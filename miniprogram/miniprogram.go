@@ -1,6 +1,8 @@
 package miniprogram
 
 import (
+	stdcontext "context"
+
 	"github.com/dcsunny/wechat/context"
 )
 
@@ -15,3 +17,8 @@ func NewMiniProgram(context *context.Context) *MiniProgram {
 	miniProgram.Context = context
 	return miniProgram
 }
+
+//WithContext 返回绑定了ctx的MiniProgram，其发起的请求受ctx的取消和超时控制
+func (wxa *MiniProgram) WithContext(ctx stdcontext.Context) *MiniProgram {
+	return NewMiniProgram(wxa.Context.WithContext(ctx))
+}
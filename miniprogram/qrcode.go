@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -50,7 +49,7 @@ func (wxa *MiniProgram) fetchCode(urlStr string, body interface{}) (response []b
 
 	urlStr = fmt.Sprintf(urlStr, accessToken)
 	var contentType string
-	response, contentType, err = util.PostJSONWithRespContentType(urlStr, body)
+	response, contentType, err = wxa.PostJSONWithRespContentType(urlStr, body)
 	if err != nil {
 		return
 	}
@@ -59,7 +58,7 @@ func (wxa *MiniProgram) fetchCode(urlStr string, body interface{}) (response []b
 		var result define.CommonError
 		err = json.Unmarshal(response, &result)
 		if err == nil && result.ErrCode != 0 {
-			err = fmt.Errorf("fetchCode error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+			err = define.NewError("fetchCode", result.ErrCode, result.ErrMsg)
 			return nil, err
 		}
 	} else if contentType == "image/jpeg" {
@@ -6,7 +6,6 @@ import (
 
 	"github.com/dcsunny/wechat/common_error"
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -39,7 +38,7 @@ func (wxa *MiniProgram) GetUserRiskRank(req UserRiskRankReq) (UserRiskRankResp,
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", getUserRiskRank, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := wxa.PostJSON(uri, req)
 	if err != nil {
 		return UserRiskRankResp{}, err
 	}
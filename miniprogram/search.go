@@ -7,7 +7,6 @@ import (
 	"github.com/dcsunny/wechat/define"
 
 	"github.com/dcsunny/wechat/common_error"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -31,7 +30,7 @@ func (wxa *MiniProgram) SearchSubmitPages(req SearchSubmitPagesReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", searchSubmitPages, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := wxa.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -65,7 +64,7 @@ func (wxa *MiniProgram) SearchSiteSearch(req SearchSiteSearchReq) (SearchSiteSea
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", searchSiteSearch, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := wxa.PostJSON(uri, req)
 	if err != nil {
 		return SearchSiteSearchResp{}, err
 	}
@@ -33,7 +33,7 @@ func (s *MiniProgram) ImgSecCheck(filename string, fileBytes []byte) (err error)
 	}
 
 	var response []byte
-	response, err = util.PostMultipartForm(fields, uri)
+	response, err = s.PostMultipartForm(fields, uri)
 	if err != nil {
 		return
 	}
@@ -43,7 +43,7 @@ func (s *MiniProgram) ImgSecCheck(filename string, fileBytes []byte) (err error)
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("ImgSecCheck error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("ImgSecCheck", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -57,7 +57,7 @@ func (s *MiniProgram) MsgSecCheck(content string) (err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", msgSecCheckUrl, accessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, map[string]interface{}{
+	response, err = s.PostJSON(uri, map[string]interface{}{
 		"content": content,
 	})
 	if err != nil {
@@ -69,7 +69,7 @@ func (s *MiniProgram) MsgSecCheck(content string) (err error) {
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("MsgSecCheck error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("MsgSecCheck", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return err
@@ -83,7 +83,7 @@ func (s *MiniProgram) MediaCheckAsync(mediaUrl string, mediaType int) (err error
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", mediaCheckAsyncUrl, accessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, map[string]interface{}{
+	response, err = s.PostJSON(uri, map[string]interface{}{
 		"media_url":  mediaUrl,
 		"media_type": mediaType,
 	})
@@ -96,7 +96,7 @@ func (s *MiniProgram) MediaCheckAsync(mediaUrl string, mediaType int) (err error
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("MediaCheckAsync error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("MediaCheckAsync", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return err
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -25,7 +24,7 @@ type ResCode2Session struct {
 func (wxa *MiniProgram) Code2Session(jsCode string) (result ResCode2Session, err error) {
 	urlStr := fmt.Sprintf(code2SessionURL, wxa.AppID, wxa.AppSecret, jsCode)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = wxa.HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -34,7 +33,7 @@ func (wxa *MiniProgram) Code2Session(jsCode string) (result ResCode2Session, err
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("Code2Session error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("Code2Session", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
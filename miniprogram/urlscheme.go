@@ -7,7 +7,6 @@ import (
 	"github.com/dcsunny/wechat/define"
 
 	"github.com/dcsunny/wechat/common_error"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -37,7 +36,7 @@ func (wxa *MiniProgram) UrlschemeGenerate(req UrlschemeGenerateReq) (UrlschemeGe
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", urlschemeGenerateUrl, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := wxa.PostJSON(uri, req)
 	if err != nil {
 		return UrlschemeGenerateResp{}, err
 	}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 type MiniSession struct {
@@ -22,7 +21,7 @@ const (
 func (oauth *Oauth) Jscode2Session(code string) (session MiniSession, err error) {
 	urlStr := fmt.Sprintf(Jscode2SessionURL, oauth.AppID, oauth.AppSecret, code)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -31,7 +30,7 @@ func (oauth *Oauth) Jscode2Session(code string) (session MiniSession, err error)
 		return
 	}
 	if session.ErrCode != 0 {
-		err = fmt.Errorf("get user session key error : errcode=%v , errmsg=%v", session.ErrCode, session.ErrMsg)
+		err = define.NewError("Jscode2Session", session.ErrCode, session.ErrMsg)
 		return
 	}
 	return
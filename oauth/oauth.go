@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,7 +11,6 @@ import (
 
 	"github.com/dcsunny/wechat/context"
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -34,6 +34,11 @@ func NewOauth(context *context.Context) *Oauth {
 	return auth
 }
 
+//WithContext 返回绑定了ctx的Oauth，其发起的请求受ctx的取消和超时控制
+func (oauth *Oauth) WithContext(ctx stdcontext.Context) *Oauth {
+	return NewOauth(oauth.Context.WithContext(ctx))
+}
+
 //GetRedirectURL 获取跳转的url地址
 func (oauth *Oauth) GetRedirectURL(redirectURI, scope, state string) (string, error) {
 	//url encode
@@ -76,7 +81,7 @@ type ResAccessToken struct {
 func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err error) {
 	urlStr := fmt.Sprintf(accessTokenURL, oauth.AppID, oauth.AppSecret, code)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -92,7 +97,7 @@ func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err
 func (oauth *Oauth) RefreshAccessToken(refreshToken string) (result ResAccessToken, err error) {
 	urlStr := fmt.Sprintf(refreshAccessTokenURL, oauth.AppID, refreshToken)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -101,7 +106,7 @@ func (oauth *Oauth) RefreshAccessToken(refreshToken string) (result ResAccessTok
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserAccessToken error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = define.NewError("RefreshAccessToken", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -111,7 +116,7 @@ func (oauth *Oauth) RefreshAccessToken(refreshToken string) (result ResAccessTok
 func (oauth *Oauth) CheckAccessToken(accessToken, openID string) (b bool, err error) {
 	urlStr := fmt.Sprintf(checkAccessTokenURL, accessToken, openID)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -147,7 +152,7 @@ type UserInfo struct {
 func (oauth *Oauth) GetUserInfo(accessToken, openID string) (result UserInfo, err error) {
 	urlStr := fmt.Sprintf(userInfoURL, accessToken, openID)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -1,6 +1,7 @@
 package pay
 
 import (
+	stdcontext "context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/define"
 	"github.com/dcsunny/wechat/util"
 )
 
@@ -18,6 +20,9 @@ const (
 	refundUri   = "https://api.mch.weixin.qq.com/secapi/pay/refund"
 )
 
+//ErrEmptyPrePayID 统一下单成功但未返回prepay_id
+var ErrEmptyPrePayID = errors.New("empty prepayid")
+
 // Pay struct extends context
 type Pay struct {
 	*context.Context
@@ -75,22 +80,6 @@ type AppPayConfig struct {
 	Sign      string `xml:"sign" json:"sign"`
 }
 
-// payResult 是 unifie order 接口的返回
-type payResult struct {
-	ReturnCode string `xml:"return_code"`
-	ReturnMsg  string `xml:"return_msg"`
-	AppID      string `xml:"appid,omitempty"`
-	MchID      string `xml:"mch_id,omitempty"`
-	NonceStr   string `xml:"nonce_str,omitempty"`
-	Sign       string `xml:"sign,omitempty"`
-	ResultCode string `xml:"result_code,omitempty"`
-	TradeType  string `xml:"trade_type,omitempty"`
-	PrePayID   string `xml:"prepay_id,omitempty"`
-	CodeURL    string `xml:"code_url,omitempty"`
-	ErrCode    string `xml:"err_code,omitempty"`
-	ErrCodeDes string `xml:"err_code_des,omitempty"`
-}
-
 //payRequest 接口请求参数
 type payRequest struct {
 	AppID          string `xml:"appid"`
@@ -157,6 +146,11 @@ func NewPay(ctx *context.Context) *Pay {
 	return &pay
 }
 
+//WithContext 返回绑定了ctx的Pay，其发起的请求受ctx的取消和超时控制
+func (pcf *Pay) WithContext(ctx stdcontext.Context) *Pay {
+	return NewPay(pcf.Context.WithContext(ctx))
+}
+
 func (pcf *Pay) PrePayIdByJs(p *Params) (prePayID string, err error) {
 	p.TradeType = "JSAPI"
 	return pcf.PrePayId(p)
@@ -195,27 +189,18 @@ func (pcf *Pay) PrePayOrder(p *Params) (payOrder PreOrder, err error) {
 	}
 	sign, err := pcf.Sign(&request, pcf.PayKey)
 	if err != nil {
-		fmt.Println(err)
 		return payOrder, err
 	}
 	request.Sign = sign
-	rawRet, err := util.PostXML(payGateway, request, nil)
+	rawRet, err := pcf.PostXML(payGateway, request, nil)
 	if err != nil {
-		return PreOrder{}, errors.New(err.Error())
+		return PreOrder{}, err
 	}
 	err = xml.Unmarshal(rawRet, &payOrder)
 	if err != nil {
-		return payOrder, errors.New(err.Error())
-	}
-	if payOrder.ReturnCode == "SUCCESS" {
-		//pay success
-		if payOrder.ResultCode == "SUCCESS" {
-			return payOrder, nil
-		}
-		return payOrder, errors.New(payOrder.ErrCode + payOrder.ErrCodeDes)
-	} else {
-		return payOrder, errors.New("[msg : xmlUnmarshalError] [rawReturn : " + string(rawRet) + "] [sign : " + sign + "]")
+		return payOrder, err
 	}
+	return payOrder, define.DecodeWithPayError(rawRet, "PrePayOrder")
 }
 
 // PrePayId will request wechat merchant api and request for a pre payment order id
@@ -225,7 +210,7 @@ func (pcf *Pay) PrePayId(p *Params) (prePayID string, err error) {
 		return
 	}
 	if order.PrePayID == "" {
-		err = errors.New("empty prepayid")
+		err = ErrEmptyPrePayID
 	}
 	prePayID = order.PrePayID
 	return
@@ -255,7 +240,6 @@ func (pcf *Pay) AppPayParams(prePayID string) AppPayConfig {
 	}
 	sign, err := pcf.Sign(&payConf, pcf.PayKey)
 	if err != nil {
-		fmt.Println(err)
 		return payConf
 	}
 	payConf.Sign = sign
@@ -300,7 +284,6 @@ func (pcf *Pay) MchPay(p *Params) error {
 	}
 	sign, err := pcf.Sign(params, pcf.PayKey)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
 	params.Sign = sign
@@ -308,26 +291,11 @@ func (pcf *Pay) MchPay(p *Params) error {
 	if err != nil {
 		return err
 	}
-	rawRet, err := util.PostXML(mchTransUri, params, client)
-	if err != nil {
-		fmt.Println(err)
-		return err
-	}
-	payRet := payResult{}
-	err = xml.Unmarshal(rawRet, &payRet)
+	rawRet, err := pcf.PostXML(mchTransUri, params, client)
 	if err != nil {
-		fmt.Println("xmlUnmarshalError,res:" + string(rawRet))
 		return err
 	}
-	if payRet.ReturnCode == "SUCCESS" {
-		if payRet.ResultCode == "SUCCESS" {
-			return nil
-		}
-		return errors.New(payRet.ErrCodeDes)
-	} else {
-		return errors.New("[msg : xmlUnmarshalError] [rawReturn : " + string(rawRet) + "]")
-	}
-	return nil
+	return define.DecodeWithPayError(rawRet, "MchPay")
 }
 
 type RedParams struct {
@@ -382,7 +350,6 @@ func (pcf *Pay) SendRed(p *Params) error {
 	}
 	sign, err := pcf.Sign(params, pcf.PayKey)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
 	params.Sign = sign
@@ -390,26 +357,11 @@ func (pcf *Pay) SendRed(p *Params) error {
 	if err != nil {
 		return err
 	}
-	rawRet, err := util.PostXML(sendRedUri, params, client)
+	rawRet, err := pcf.PostXML(sendRedUri, params, client)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
-	payRet := RedResult{}
-	err = xml.Unmarshal(rawRet, &payRet)
-	if err != nil {
-		fmt.Println("xmlUnmarshalError,res:" + string(rawRet))
-		return err
-	}
-	if payRet.ReturnCode == "SUCCESS" {
-		if payRet.ResultCode == "SUCCESS" {
-			return nil
-		}
-		return errors.New(payRet.ErrCodeDes)
-	} else {
-		return errors.New("[msg : xmlUnmarshalError] [rawReturn : " + string(rawRet) + "]")
-	}
-	return nil
+	return define.DecodeWithPayError(rawRet, "SendRed")
 }
 
 type WxRefundParams struct {
@@ -474,7 +426,6 @@ func (pcf *Pay) Refund(p *RefundParams) error {
 	}
 	sign, err := pcf.Sign(params, pcf.PayKey)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
 	params.Sign = sign
@@ -482,24 +433,9 @@ func (pcf *Pay) Refund(p *RefundParams) error {
 	if err != nil {
 		return err
 	}
-	rawRet, err := util.PostXML(refundUri, params, client)
+	rawRet, err := pcf.PostXML(refundUri, params, client)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
-	payRet := RefundResult{}
-	err = xml.Unmarshal(rawRet, &payRet)
-	if err != nil {
-		fmt.Println("xmlUnmarshalError,res:" + string(rawRet))
-		return err
-	}
-	if payRet.ReturnCode == "SUCCESS" {
-		if payRet.ResultCode == "SUCCESS" {
-			return nil
-		}
-		return errors.New(payRet.ErrCodeDes)
-	} else {
-		return errors.New("[msg : xmlUnmarshalError] [rawReturn : " + string(rawRet) + "]")
-	}
-	return nil
+	return define.DecodeWithPayError(rawRet, "Refund")
 }
@@ -1,6 +1,7 @@
 package qr
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -10,7 +11,6 @@ import (
 
 	"github.com/dcsunny/wechat/context"
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -38,6 +38,11 @@ func NewQR(context *context.Context) *QR {
 	return q
 }
 
+//WithContext 返回绑定了ctx的QR，其发起的请求受ctx的取消和超时控制
+func (q *QR) WithContext(ctx stdcontext.Context) *QR {
+	return NewQR(q.Context.WithContext(ctx))
+}
+
 // Request 临时二维码
 type Request struct {
 	ExpireSeconds int64  `json:"expire_seconds,omitempty"`
@@ -66,7 +71,7 @@ func (q *QR) GetQRTicket(tq *Request) (t *Ticket, err error) {
 	}
 
 	uri := fmt.Sprintf(qrCreateURL, accessToken)
-	response, err := util.PostJSON(uri, tq)
+	response, err := q.PostJSON(uri, tq)
 	if err != nil {
 		err = fmt.Errorf("get qr ticket failed, %s", err)
 		return
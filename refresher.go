@@ -0,0 +1,249 @@
+package wechat
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/js"
+)
+
+const (
+	defaultRefreshAhead         = 5 * time.Minute
+	defaultRefreshRetryInterval = 30 * time.Second
+	//defaultExpiresIn 凭据已由其他实例刷新、无法得知有效期时按微信默认的7200秒计算
+	defaultExpiresIn = 7200
+)
+
+//刷新任务名称，作为OnError的name参数
+const (
+	RefreshAccessToken     = "access_token"
+	RefreshJsAPITicket     = "jsapi_ticket"
+	RefreshComponentToken  = "component_access_token"
+	RefreshAuthorizerToken = "authorizer_access_token"
+)
+
+//RefresherConfig 后台刷新配置
+type RefresherConfig struct {
+	Ahead         time.Duration //在缓存过期前多久刷新，默认5分钟
+	RetryInterval time.Duration //刷新失败后的重试间隔，默认30秒
+
+	DisableAccessToken bool //不刷新access_token，如第三方平台或使用自定义GetAccessTokenFunc时
+	JsAPITicket        bool //同时刷新js-sdk的jsapi_ticket
+
+	//ComponentToken 刷新第三方平台的component_access_token，使用通过SetComponentVerifyTicket保存的component_verify_ticket
+	ComponentToken bool
+	//ComponentVerifyTicket 返回最新的component_verify_ticket，设置后刷新第三方平台的component_access_token，
+	//未设置时使用ComponentStore中保存的ticket
+	ComponentVerifyTicket func() (string, error)
+	//AuthorizerTokens 刷新各授权方的access_token，授权方通过Authorizers获取，未设置Authorizers时通过IterAuthorizers拉取授权方列表
+	AuthorizerTokens bool
+	//Authorizers 返回授权方appid到authorizer_refresh_token的映射，设置后刷新各授权方的access_token
+	Authorizers func() (map[string]string, error)
+
+	//OnError 刷新失败时回调，name为刷新任务名称(RefreshAccessToken等)
+	OnError func(name string, err error)
+}
+
+//Refresher 在access_token等凭据的缓存过期前主动刷新，避免在用户请求中同步获取。
+//多实例部署时刷新通过Config.Locker加锁，但只需在一个实例上启动
+type Refresher struct {
+	wc   *Wechat
+	cfg  RefresherConfig
+	jobs []refreshJob
+
+	mu     sync.Mutex
+	cancel stdcontext.CancelFunc
+	done   chan struct{}
+}
+
+type refreshJob struct {
+	name string
+	//refresh 刷新凭据并返回其缓存时间
+	refresh func(ctx *context.Context) (time.Duration, error)
+}
+
+//NewRefresher 创建后台刷新器，调用Start后开始刷新
+func (wc *Wechat) NewRefresher(cfg *RefresherConfig) *Refresher {
+	r := &Refresher{wc: wc, cfg: *cfg}
+	if r.cfg.Ahead <= 0 {
+		r.cfg.Ahead = defaultRefreshAhead
+	}
+	if r.cfg.RetryInterval <= 0 {
+		r.cfg.RetryInterval = defaultRefreshRetryInterval
+	}
+	if !r.cfg.DisableAccessToken {
+		r.jobs = append(r.jobs, refreshJob{RefreshAccessToken, refreshAccessToken})
+	}
+	if r.cfg.JsAPITicket {
+		r.jobs = append(r.jobs, refreshJob{RefreshJsAPITicket, refreshJsAPITicket})
+	}
+	if r.cfg.ComponentToken || r.cfg.ComponentVerifyTicket != nil {
+		r.jobs = append(r.jobs, refreshJob{RefreshComponentToken, r.refreshComponentToken})
+	}
+	if r.cfg.AuthorizerTokens || r.cfg.Authorizers != nil {
+		r.jobs = append(r.jobs, refreshJob{RefreshAuthorizerToken, r.refreshAuthorizerTokens})
+	}
+	return r
+}
+
+//Start 启动后台刷新，启动时立即刷新一次，重复调用无效
+func (r *Refresher) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done != nil {
+		return
+	}
+	var c stdcontext.Context
+	c, r.cancel = stdcontext.WithCancel(stdcontext.Background())
+	r.done = make(chan struct{})
+	go r.run(c, r.done)
+}
+
+//Stop 停止后台刷新，取消正在进行的请求并等待刷新协程退出
+func (r *Refresher) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel, r.done = nil, nil
+}
+
+func (r *Refresher) run(c stdcontext.Context, done chan struct{}) {
+	defer close(done)
+	ctx := r.wc.Context.WithContext(c)
+	next := make([]time.Time, len(r.jobs))
+	for {
+		var wait time.Duration
+		for i, job := range r.jobs {
+			now := time.Now()
+			if !now.Before(next[i]) {
+				next[i] = now.Add(r.refresh(ctx, job))
+			}
+			if d := next[i].Sub(now); i == 0 || d < wait {
+				wait = d
+			}
+		}
+		if len(r.jobs) == 0 {
+			<-c.Done()
+			return
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-c.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+//refresh 执行刷新任务并返回距下次刷新的时间
+func (r *Refresher) refresh(ctx *context.Context, job refreshJob) time.Duration {
+	lifetime, err := job.refresh(ctx)
+	if err != nil {
+		if r.cfg.OnError != nil && ctx.StdContext().Err() == nil {
+			r.cfg.OnError(job.name, err)
+		}
+		return r.cfg.RetryInterval
+	}
+	if d := lifetime - r.cfg.Ahead; d > r.cfg.RetryInterval {
+		return d
+	}
+	return r.cfg.RetryInterval
+}
+
+func refreshAccessToken(ctx *context.Context) (time.Duration, error) {
+	resAccessToken, err := ctx.RefreshAccessToken()
+	if err != nil {
+		return 0, err
+	}
+	return cacheExpires(resAccessToken.ExpiresIn), nil
+}
+
+//cacheExpires 按有效期计算缓存时间，expiresIn为0(已由其他实例刷新)时按默认有效期计算
+func cacheExpires(expiresIn int64) time.Duration {
+	if expiresIn <= 0 {
+		expiresIn = defaultExpiresIn
+	}
+	return context.CacheExpires(expiresIn)
+}
+
+func refreshJsAPITicket(ctx *context.Context) (time.Duration, error) {
+	_, expiresIn, err := js.NewJs(ctx).RefreshTicket()
+	if err != nil {
+		return 0, err
+	}
+	return cacheExpires(expiresIn), nil
+}
+
+func (r *Refresher) refreshComponentToken(ctx *context.Context) (time.Duration, error) {
+	getVerifyTicket := r.cfg.ComponentVerifyTicket
+	if getVerifyTicket == nil {
+		getVerifyTicket = ctx.GetComponentVerifyTicket
+	}
+	verifyTicket, err := getVerifyTicket()
+	if err != nil {
+		return 0, err
+	}
+	if verifyTicket == "" {
+		return 0, errors.New("component_verify_ticket is empty")
+	}
+	at, err := ctx.SetComponentAccessToken(verifyTicket)
+	if err != nil {
+		return 0, err
+	}
+	return cacheExpires(at.ExpiresIn), nil
+}
+
+//refreshAuthorizerTokens 刷新各授权方的access_token，按其中最早过期的时间安排下次刷新
+func (r *Refresher) refreshAuthorizerTokens(ctx *context.Context) (time.Duration, error) {
+	var lifetime time.Duration
+	var failed int
+	refresh := func(appid, refreshToken string) {
+		token, err := ctx.RefreshAuthrToken(appid, refreshToken)
+		if err != nil {
+			failed++
+			//单个授权方失败（如已取消授权）不影响其他授权方，错误直接回调
+			if r.cfg.OnError != nil && ctx.StdContext().Err() == nil {
+				r.cfg.OnError(RefreshAuthorizerToken, fmt.Errorf("authorizer %s: %w", appid, err))
+			}
+			return
+		}
+		if d := cacheExpires(token.ExpiresIn); lifetime == 0 || d < lifetime {
+			lifetime = d
+		}
+	}
+
+	if r.cfg.Authorizers != nil {
+		authorizers, err := r.cfg.Authorizers()
+		if err != nil {
+			return 0, err
+		}
+		for appid, refreshToken := range authorizers {
+			refresh(appid, refreshToken)
+		}
+	} else {
+		iter := ctx.IterAuthorizers(0)
+		for iter.Next() {
+			authorizer := iter.Authorizer()
+			refresh(authorizer.AuthorizerAppid, authorizer.RefreshToken)
+		}
+		if err := iter.Err(); err != nil {
+			return 0, err
+		}
+	}
+	if lifetime == 0 && failed == 0 {
+		//没有授权方
+		lifetime = cacheExpires(0)
+	}
+	//全部失败时lifetime为0，按RetryInterval重试
+	return lifetime, nil
+}
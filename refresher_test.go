@@ -0,0 +1,242 @@
+package wechat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/context"
+)
+
+func TestRefresher(t *testing.T) {
+	var tokenRequests int32
+	refreshed := make(chan struct{}, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/token":
+			atomic.AddInt32(&tokenRequests, 1)
+			w.Write([]byte(`{"access_token":"token","expires_in":2}`))
+			select {
+			case refreshed <- struct{}{}:
+			default:
+			}
+		default:
+			w.Write([]byte(`{"errcode":40164,"errmsg":"invalid ip"}`))
+		}
+	}))
+	defer ts.Close()
+
+	wc := NewWechat(&Config{AppID: "appid", AppSecret: "secret", APIHost: ts.URL, Cache: cache.NewMemory()})
+	errs := make(chan string, 10)
+	r := wc.NewRefresher(&RefresherConfig{
+		Ahead:         900 * time.Millisecond,
+		RetryInterval: 50 * time.Millisecond,
+		JsAPITicket:   true,
+		OnError: func(name string, err error) {
+			select {
+			case errs <- name:
+			default:
+			}
+		},
+	})
+	r.Start()
+	//启动时刷新一次，缓存过期前再刷新一次
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-refreshed:
+		case <-timeout:
+			r.Stop()
+			t.Fatalf("expect access_token refreshed ahead of expiry, got %d requests", atomic.LoadInt32(&tokenRequests))
+		}
+	}
+	select {
+	case name := <-errs:
+		if name != RefreshJsAPITicket {
+			t.Errorf("expect jsapi_ticket error, got %s", name)
+		}
+	case <-timeout:
+		t.Error("expect jsapi_ticket error")
+	}
+	r.Stop()
+
+	if accessToken, _ := wc.GetAccessToken(); accessToken != "token" {
+		t.Errorf("expect cached token, got %s", accessToken)
+	}
+	//Stop等待刷新协程退出，之后不再有请求
+	n := atomic.LoadInt32(&tokenRequests)
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt32(&tokenRequests) != n {
+		t.Error("expect no refresh after Stop")
+	}
+}
+
+func TestRefresherAccessTokenLock(t *testing.T) {
+	var tokenRequests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenRequests, 1)
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprintf(w, `{"access_token":"token%d","expires_in":7200}`, n)
+	}))
+	defer ts.Close()
+
+	//两个实例共用缓存及分布式锁，同时刷新时只请求一次
+	shared := cache.NewMemory()
+	var wg sync.WaitGroup
+	lifetimes := make([]time.Duration, 2)
+	for i := range lifetimes {
+		wc := NewWechat(&Config{AppID: "appid", AppSecret: "secret", APIHost: ts.URL, Cache: shared})
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lifetime, err := refreshAccessToken(wc.Context)
+			if err != nil {
+				t.Error(err)
+			}
+			lifetimes[i] = lifetime
+		}(i)
+	}
+	wg.Wait()
+	if tokenRequests != 1 {
+		t.Errorf("expect 1 token request, got %d", tokenRequests)
+	}
+	for _, lifetime := range lifetimes {
+		if lifetime != context.CacheExpires(7200) {
+			t.Errorf("unexpected lifetime %v", lifetime)
+		}
+	}
+
+	//自定义GetAccessTokenFunc时不请求微信
+	wc := NewWechat(&Config{AppID: "appid", AppSecret: "secret", APIHost: ts.URL, Cache: cache.NewMemory()})
+	wc.Context.SetGetAccessTokenFunc(func(ctx *context.Context) (string, error) {
+		return "custom", nil
+	})
+	if _, err := refreshAccessToken(wc.Context); err != nil || tokenRequests != 1 {
+		t.Errorf("expect custom GetAccessTokenFunc used, got %d requests %v", tokenRequests, err)
+	}
+}
+
+func TestRefresherJsAPITicketLock(t *testing.T) {
+	var ticketRequests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/token":
+			w.Write([]byte(`{"access_token":"token","expires_in":7200}`))
+		case "/cgi-bin/ticket/getticket":
+			n := atomic.AddInt32(&ticketRequests, 1)
+			time.Sleep(100 * time.Millisecond)
+			fmt.Fprintf(w, `{"errcode":0,"ticket":"ticket%d","expires_in":7200}`, n)
+		}
+	}))
+	defer ts.Close()
+
+	//两个实例共用缓存及分布式锁，同时刷新时只请求一次
+	shared := cache.NewMemory()
+	var wg sync.WaitGroup
+	lifetimes := make([]time.Duration, 2)
+	for i := range lifetimes {
+		wc := NewWechat(&Config{AppID: "appid", AppSecret: "secret", APIHost: ts.URL, Cache: shared})
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lifetime, err := refreshJsAPITicket(wc.Context)
+			if err != nil {
+				t.Error(err)
+			}
+			lifetimes[i] = lifetime
+		}(i)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&ticketRequests); n != 1 {
+		t.Errorf("expect 1 ticket request, got %d", n)
+	}
+	for _, lifetime := range lifetimes {
+		if lifetime != context.CacheExpires(7200) {
+			t.Errorf("unexpected lifetime %v", lifetime)
+		}
+	}
+}
+
+func TestRefresherAuthorizers(t *testing.T) {
+	var refreshed []string
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/component/api_component_token":
+			w.Write([]byte(`{"component_access_token":"component_token","expires_in":7200}`))
+		case "/cgi-bin/component/api_get_authorizer_list":
+			w.Write([]byte(`{"total_count":2,"list":[{"authorizer_appid":"wxa","refresh_token":"ra"},{"authorizer_appid":"wxb","refresh_token":"rb"}]}`))
+		case "/cgi-bin/component/api_authorizer_token":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			refreshed = append(refreshed, req["authorizer_appid"]+":"+req["authorizer_refresh_token"])
+			mu.Unlock()
+			expiresIn := 7200
+			if req["authorizer_appid"] == "wxb" {
+				expiresIn = 3600
+			}
+			fmt.Fprintf(w, `{"authorizer_access_token":"token_%s","expires_in":%d,"authorizer_refresh_token":"%s"}`, req["authorizer_appid"], expiresIn, req["authorizer_refresh_token"])
+		}
+	}))
+	defer ts.Close()
+
+	wc := NewWechat(&Config{AppID: "component_appid", AppSecret: "secret", APIHost: ts.URL, Cache: cache.NewMemory()})
+	if err := wc.Context.SetComponentVerifyTicket("ticket"); err != nil {
+		t.Fatal(err)
+	}
+	r := wc.NewRefresher(&RefresherConfig{DisableAccessToken: true, AuthorizerTokens: true})
+	if len(r.jobs) != 1 || r.jobs[0].name != RefreshAuthorizerToken {
+		t.Fatalf("expect authorizer refresh job, got %+v", r.jobs)
+	}
+	lifetime, err := r.refreshAuthorizerTokens(wc.Context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(refreshed, ",") != "wxa:ra,wxb:rb" {
+		t.Errorf("expect authorizers from IterAuthorizers refreshed, got %v", refreshed)
+	}
+	//按最早过期的授权方安排下次刷新
+	if lifetime != context.CacheExpires(3600) {
+		t.Errorf("expect lifetime from expires_in, got %v", lifetime)
+	}
+}
+
+func TestRefresherComponentToken(t *testing.T) {
+	var tickets []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		tickets = append(tickets, req["component_verify_ticket"])
+		fmt.Fprintf(w, `{"component_access_token":"component_token%d","expires_in":7200}`, len(tickets))
+	}))
+	defer ts.Close()
+
+	wc := NewWechat(&Config{AppID: "component_appid", AppSecret: "secret", APIHost: ts.URL, Cache: cache.NewMemory()})
+	r := wc.NewRefresher(&RefresherConfig{DisableAccessToken: true, ComponentToken: true})
+	if len(r.jobs) != 1 || r.jobs[0].name != RefreshComponentToken {
+		t.Fatalf("expect component token refresh job, got %+v", r.jobs)
+	}
+	if _, err := r.refreshComponentToken(wc.Context); err == nil {
+		t.Error("expect error before component_verify_ticket received")
+	}
+
+	//未设置ComponentVerifyTicket时使用SetComponentVerifyTicket保存的ticket
+	if err := wc.Context.SetComponentVerifyTicket("stored_ticket"); err != nil {
+		t.Fatal(err)
+	}
+	lifetime, err := r.refreshComponentToken(wc.Context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tickets, ",") != "stored_ticket" || lifetime != context.CacheExpires(7200) {
+		t.Errorf("expect stored ticket used, got %v %v", tickets, lifetime)
+	}
+}
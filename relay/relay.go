@@ -0,0 +1,328 @@
+//Package relay 提供集中获取access_token和jsapi_ticket的中控服务，
+//各服务将Config.AccessTokenURL、Config.JsAPITicketURL指向它，避免各自刷新导致token互相失效。
+//调用方遇到access_token失效时通过invalid_token参数通知中控服务刷新
+package relay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcsunny/wechat"
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/define"
+	"github.com/dcsunny/wechat/js"
+)
+
+const (
+	//TimestampHeader HMAC校验时携带时间戳(秒)的请求头
+	TimestampHeader = "X-Wechat-Relay-Timestamp"
+	//NonceHeader HMAC校验时携带随机串的请求头
+	NonceHeader = "X-Wechat-Relay-Nonce"
+	//SignatureHeader HMAC校验时携带签名的请求头
+	SignatureHeader = "X-Wechat-Relay-Signature"
+
+	defaultMaxSkew = 5 * time.Minute
+
+	accessTokenCacheKey = "relay_access_token:%s"
+	jsAPITicketCacheKey = "relay_jsapi_ticket:%s"
+	accessTokenLockKey  = "relay_access_token_lock:%s"
+	jsAPITicketLockKey  = "relay_jsapi_ticket_lock:%s"
+	nonceCacheKey       = "relay_nonce:%s:%s"
+
+	//unknownExpiresIn access_token已由其他请求刷新、无法得知剩余有效期时的缓存时间(秒)，过期后重新从微信服务器获取
+	unknownExpiresIn = 300
+)
+
+//与微信一致的错误码，调用方按微信接口的错误处理
+const (
+	errCodeSystem       = -1
+	errCodeInvalidAppID = 40013
+	errCodeUnauthorized = 40125
+)
+
+//Config 中控服务配置
+type Config struct {
+	//Apps 托管的公众号/小程序，使用其中的Cache、Locker、HTTPClient、StableAccessToken等配置
+	Apps []*wechat.Config
+	//Secret 调用方的共享密钥，为空时不校验
+	Secret string
+	//HMAC 为true时调用方需用Secret对请求签名(见Sign)，否则通过key参数或Authorization: Bearer传递Secret
+	HMAC bool
+	//MaxSkew HMAC签名时间戳允许的误差，默认5分钟
+	MaxSkew time.Duration
+	//NonceCache 记录HMAC签名使用过的nonce，拒绝MaxSkew的两倍时间内重复的请求，为空时使用appid对应配置的Cache
+	NonceCache cache.Cache
+}
+
+//Server 中控服务，实现http.Handler：
+//路径以ticket结尾时返回jsapi_ticket，其余返回access_token，通过appid参数指定公众号；
+//获取access_token时携带invalid_token参数且其为当前缓存的token时，删除并从微信服务器重新获取
+type Server struct {
+	secret     string
+	hmac       bool
+	maxSkew    time.Duration
+	nonceCache cache.Cache
+
+	apps map[string]*app
+}
+
+type app struct {
+	ctx *context.Context
+	mu  sync.Mutex
+}
+
+//entry 缓存的凭据及其过期时间，用于返回剩余有效期
+type entry struct {
+	Value    string `json:"value"`
+	ExpireAt int64  `json:"expire_at"`
+}
+
+//ticketResponse jsapi_ticket返回结果，与微信getticket接口一致
+type ticketResponse struct {
+	define.CommonError
+
+	Ticket    string `json:"ticket"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+//NewServer 创建中控服务
+func NewServer(cfg *Config) *Server {
+	srv := &Server{
+		secret:     cfg.Secret,
+		hmac:       cfg.HMAC,
+		maxSkew:    cfg.MaxSkew,
+		nonceCache: cfg.NonceCache,
+		apps:       make(map[string]*app, len(cfg.Apps)),
+	}
+	if srv.maxSkew <= 0 {
+		srv.maxSkew = defaultMaxSkew
+	}
+	for _, appCfg := range cfg.Apps {
+		srv.apps[appCfg.AppID] = &app{ctx: wechat.NewWechat(appCfg).Context}
+	}
+	return srv
+}
+
+//ServeHTTP 处理获取access_token、jsapi_ticket的请求，与微信接口一样总是返回200，错误通过errcode返回
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	appID := r.URL.Query().Get("appid")
+	if err := srv.authenticate(r, appID); err != nil {
+		writeJSON(w, define.CommonError{ErrCode: errCodeUnauthorized, ErrMsg: err.Error()})
+		return
+	}
+	a, ok := srv.apps[appID]
+	if !ok {
+		writeJSON(w, define.CommonError{ErrCode: errCodeInvalidAppID, ErrMsg: fmt.Sprintf("invalid appid %q", appID)})
+		return
+	}
+	if err := srv.checkNonce(r, a); err != nil {
+		writeJSON(w, define.CommonError{ErrCode: errCodeUnauthorized, ErrMsg: err.Error()})
+		return
+	}
+	ctx := a.ctx.WithContext(r.Context())
+
+	if strings.HasSuffix(r.URL.Path, "ticket") {
+		e, err := a.load(ctx, jsAPITicketCacheKey, jsAPITicketLockKey, func() (string, int64, error) {
+			return js.NewJs(ctx).RefreshTicket()
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, ticketResponse{Ticket: e.Value, ExpiresIn: e.expiresIn()})
+		return
+	}
+
+	fetch := func() (string, int64, error) {
+		res, err := ctx.GetAccessTokenFromServer()
+		return res.AccessToken, res.ExpiresIn, err
+	}
+	var e *entry
+	var err error
+	if invalidToken := r.URL.Query().Get("invalid_token"); invalidToken != "" {
+		e, err = a.refreshInvalid(ctx, invalidToken, fetch)
+	} else {
+		e, err = a.load(ctx, accessTokenCacheKey, accessTokenLockKey, fetch)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, context.ResAccessToken{AccessToken: e.Value, ExpiresIn: e.expiresIn()})
+}
+
+//load 从缓存读取凭据，不存在时在锁的保护下通过fetch从微信服务器获取
+func (a *app) load(ctx *context.Context, cacheKey, lockKey string, fetch func() (string, int64, error)) (*entry, error) {
+	cacheKey = fmt.Sprintf(cacheKey, ctx.AppID)
+	get := func() string {
+		return ctx.Cache.GetString(cacheKey)
+	}
+	raw := get()
+	if raw == "" {
+		a.mu.Lock()
+		var err error
+		raw, err = ctx.RefreshWithLock(fmt.Sprintf(lockKey, ctx.AppID), get, func() (string, error) {
+			value, expiresIn, err := fetch()
+			if err != nil {
+				return "", err
+			}
+			data, err := json.Marshal(entry{Value: value, ExpireAt: time.Now().Unix() + expiresIn})
+			if err != nil {
+				return "", err
+			}
+			if err := ctx.Cache.SetString(cacheKey, string(data), context.CacheExpires(expiresIn)); err != nil {
+				return "", err
+			}
+			return string(data), nil
+		})
+		a.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	e := new(entry)
+	if err := json.Unmarshal([]byte(raw), e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+//refreshInvalid 调用方报告invalidToken失效：若其为当前缓存的access_token，在锁的保护下通过RefreshInvalidAccessTokenWithExpiresIn
+//重新获取，已被其他请求刷新或不是中控服务签发的token时返回当前缓存的token
+func (a *app) refreshInvalid(ctx *context.Context, invalidToken string, fetch func() (string, int64, error)) (*entry, error) {
+	a.mu.Lock()
+	cacheKey := fmt.Sprintf(accessTokenCacheKey, ctx.AppID)
+	raw := ctx.Cache.GetString(cacheKey)
+	var cached entry
+	if raw == "" || json.Unmarshal([]byte(raw), &cached) != nil || cached.Value != invalidToken {
+		a.mu.Unlock()
+		return a.load(ctx, accessTokenCacheKey, accessTokenLockKey, fetch)
+	}
+	defer a.mu.Unlock()
+	res, ok, err := ctx.RefreshInvalidAccessTokenWithExpiresIn(invalidToken)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		//公众号自身的缓存中已是其他token，直接使用
+		res.AccessToken, err = ctx.GetAccessToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	//使用微信返回的expires_in，稳定版access_token返回的是剩余有效期
+	expiresIn := res.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = unknownExpiresIn
+	}
+	e := &entry{Value: res.AccessToken, ExpireAt: time.Now().Unix() + expiresIn}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Cache.SetString(cacheKey, string(data), context.CacheExpires(expiresIn)); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+//expiresIn 剩余有效期(秒)
+func (e *entry) expiresIn() int64 {
+	if expiresIn := e.ExpireAt - time.Now().Unix(); expiresIn > 0 {
+		return expiresIn
+	}
+	return 0
+}
+
+//authenticate 校验调用方
+func (srv *Server) authenticate(r *http.Request, appID string) error {
+	if srv.secret == "" {
+		return nil
+	}
+	if !srv.hmac {
+		key := r.URL.Query().Get("key")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			key = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(key), []byte(srv.secret)) != 1 {
+			return errors.New("invalid relay secret")
+		}
+		return nil
+	}
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return errors.New("invalid relay timestamp")
+	}
+	if skew := time.Since(time.Unix(timestamp, 0)); skew > srv.maxSkew || skew < -srv.maxSkew {
+		return errors.New("relay timestamp expired")
+	}
+	if r.Header.Get(NonceHeader) == "" {
+		return errors.New("missing relay nonce")
+	}
+	expected := Sign(srv.secret, r.Method, r.URL.Path, appID, r.URL.Query().Get("invalid_token"), r.Header.Get(TimestampHeader), r.Header.Get(NonceHeader))
+	if !hmac.Equal([]byte(r.Header.Get(SignatureHeader)), []byte(expected)) {
+		return errors.New("invalid relay signature")
+	}
+	return nil
+}
+
+//checkNonce HMAC校验时记录nonce，拒绝重放的请求
+func (srv *Server) checkNonce(r *http.Request, a *app) error {
+	if srv.secret == "" || !srv.hmac {
+		return nil
+	}
+	c := srv.nonceCache
+	if c == nil {
+		c = a.ctx.Cache
+	}
+	key := fmt.Sprintf(nonceCacheKey, a.ctx.AppID, r.Header.Get(NonceHeader))
+	expiration := 2 * srv.maxSkew
+	if locker, ok := c.(cache.Locker); ok {
+		acquired, err := locker.Acquire(key, "1", expiration)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			return errors.New("relay nonce reused")
+		}
+		return nil
+	}
+	if c.IsExist(key) {
+		return errors.New("relay nonce reused")
+	}
+	return c.SetString(key, "1", expiration)
+}
+
+//Sign 计算HMAC-SHA256签名：hex(HMAC(secret, method + "\n" + path + "\n" + appid + "\n" + invalid_token + "\n" + timestamp + "\n" + nonce))，
+//path为中控服务收到的请求路径，经过改写路径的反向代理时需保持一致；invalidToken为invalid_token参数，没有时为空
+func Sign(secret, method, path, appID, invalidToken, timestamp, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + path + "\n" + appID + "\n" + invalidToken + "\n" + timestamp + "\n" + nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *define.Error
+	if errors.As(err, &apiErr) {
+		writeJSON(w, define.CommonError{ErrCode: apiErr.ErrCode, ErrMsg: apiErr.ErrMsg})
+		return
+	}
+	writeJSON(w, define.CommonError{ErrCode: errCodeSystem, ErrMsg: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(obj)
+}
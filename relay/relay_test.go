@@ -0,0 +1,183 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat"
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/define"
+)
+
+func TestServer(t *testing.T) {
+	var tokenRequests int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/token":
+			atomic.AddInt32(&tokenRequests, 1)
+			w.Write([]byte(`{"access_token":"token","expires_in":7200}`))
+		case "/cgi-bin/ticket/getticket":
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","ticket":"ticket","expires_in":7200}`))
+		}
+	}))
+	defer upstream.Close()
+
+	ts := httptest.NewServer(NewServer(&Config{
+		Apps: []*wechat.Config{
+			{AppID: "appid", AppSecret: "secret", APIHost: upstream.URL, Cache: cache.NewMemory()},
+		},
+		Secret: "relay_secret",
+		HMAC:   true,
+	}))
+	defer ts.Close()
+	relayURL, _ := url.Parse(ts.URL)
+
+	for i := 0; i < 2; i++ {
+		wc := wechat.NewWechat(&wechat.Config{
+			AppID:          "appid",
+			AccessTokenURL: ts.URL + "/token",
+			JsAPITicketURL: ts.URL + "/ticket",
+			Cache:          cache.NewMemory(),
+			HTTPClient:     &http.Client{Transport: &Transport{Host: relayURL.Host, Secret: "relay_secret"}},
+		})
+		if accessToken, err := wc.GetAccessToken(); accessToken != "token" || err != nil {
+			t.Errorf("expect token from relay, got %s %v", accessToken, err)
+		}
+		if ticket, err := wc.GetJs().GetTicket(); ticket != "ticket" || err != nil {
+			t.Errorf("expect ticket from relay, got %s %v", ticket, err)
+		}
+	}
+	if tokenRequests != 1 {
+		t.Errorf("expect 1 token request to upstream, got %d", tokenRequests)
+	}
+
+	wc := wechat.NewWechat(&wechat.Config{
+		AppID:          "appid",
+		AccessTokenURL: ts.URL + "/token",
+		Cache:          cache.NewMemory(),
+		HTTPClient:     &http.Client{Transport: &Transport{Host: relayURL.Host, Secret: "wrong"}},
+	})
+	var apiErr *define.Error
+	if _, err := wc.GetAccessToken(); !errors.As(err, &apiErr) || apiErr.ErrCode != errCodeUnauthorized {
+		t.Errorf("expect unauthorized error, got %v", err)
+	}
+}
+
+func TestServerInvalidToken(t *testing.T) {
+	var tokenRequests int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenRequests, 1)
+		//模拟稳定版接口，强制刷新后返回的是剩余有效期
+		expiresIn := 7200
+		if n > 1 {
+			expiresIn = 3000
+		}
+		fmt.Fprintf(w, `{"access_token":"token%d","expires_in":%d}`, n, expiresIn)
+	}))
+	defer upstream.Close()
+
+	ts := httptest.NewServer(NewServer(&Config{
+		Apps: []*wechat.Config{
+			{AppID: "appid", AppSecret: "secret", APIHost: upstream.URL, Cache: cache.NewMemory()},
+		},
+	}))
+	defer ts.Close()
+
+	clients := make([]*wechat.Wechat, 2)
+	for i := range clients {
+		clients[i] = wechat.NewWechat(&wechat.Config{AppID: "appid", AccessTokenURL: ts.URL + "/token", Cache: cache.NewMemory()})
+		if accessToken, err := clients[i].GetAccessToken(); accessToken != "token1" || err != nil {
+			t.Fatalf("expect token1 from relay, got %s %v", accessToken, err)
+		}
+	}
+
+	//调用方报告token失效后中控服务重新获取，其他调用方再次报告时直接返回新token
+	for _, wc := range clients {
+		accessToken, ok, err := wc.Context.RefreshInvalidAccessToken("token1")
+		if !ok || err != nil || accessToken != "token2" {
+			t.Errorf("expect token2 after invalid token reported, got %s %v %v", accessToken, ok, err)
+		}
+	}
+	if tokenRequests != 2 {
+		t.Errorf("expect 2 token requests to upstream, got %d", tokenRequests)
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	resp, err := http.Get(ts.URL + "/token?appid=appid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(resp.Body).Decode(&res)
+	resp.Body.Close()
+	if res.AccessToken != "token2" || res.ExpiresIn > 3000 || res.ExpiresIn < 2990 {
+		t.Errorf("expect expires_in returned by wechat after refresh, got %+v", res)
+	}
+
+	//不是中控服务签发的token不触发刷新
+	resp, err = http.Get(ts.URL + "/token?appid=appid&invalid_token=foreign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if tokenRequests != 2 {
+		t.Errorf("expect foreign token ignored, got %d token requests", tokenRequests)
+	}
+}
+
+func TestServerHMACReplay(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"token","expires_in":7200}`))
+	}))
+	defer upstream.Close()
+	ts := httptest.NewServer(NewServer(&Config{
+		Apps:   []*wechat.Config{{AppID: "appid", AppSecret: "secret", APIHost: upstream.URL, Cache: cache.NewMemory()}},
+		Secret: "relay_secret",
+		HMAC:   true,
+	}))
+	defer ts.Close()
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	get := func(path, signedPath, invalidToken, signedInvalidToken, nonce string) int64 {
+		query := url.Values{"appid": {"appid"}}
+		if invalidToken != "" {
+			query.Set("invalid_token", invalidToken)
+		}
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path+"?"+query.Encode(), nil)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(NonceHeader, nonce)
+		req.Header.Set(SignatureHeader, Sign("relay_secret", http.MethodGet, signedPath, "appid", signedInvalidToken, timestamp, nonce))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var res define.CommonError
+		json.NewDecoder(resp.Body).Decode(&res)
+		return res.ErrCode
+	}
+	if code := get("/ticket", "/token", "", "", "nonce"); code != errCodeUnauthorized {
+		t.Errorf("expect signature bound to path, got errcode %d", code)
+	}
+	if code := get("/token", "/token", "token", "", "nonce1"); code != errCodeUnauthorized {
+		t.Errorf("expect signature bound to invalid_token, got errcode %d", code)
+	}
+	if code := get("/token", "/token", "", "", "nonce"); code != 0 {
+		t.Errorf("expect signed request accepted, got errcode %d", code)
+	}
+	if code := get("/token", "/token", "", "", "nonce"); code != errCodeUnauthorized {
+		t.Errorf("expect replayed request rejected, got errcode %d", code)
+	}
+	if code := get("/token", "/token", "token", "token", "nonce2"); code != 0 {
+		t.Errorf("expect signed invalid_token accepted, got errcode %d", code)
+	}
+}
@@ -0,0 +1,40 @@
+package relay
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dcsunny/wechat/util"
+)
+
+//Transport 为发往中控服务的请求添加HMAC签名，其他请求原样发送，
+//调用方将其设置到Config.HTTPClient中使用
+type Transport struct {
+	//Host 中控服务的host，只有发往该host的请求才会签名
+	Host string
+	//Secret 与中控服务约定的密钥
+	Secret string
+	//Base 实际发送请求的RoundTripper，为空时使用http.DefaultTransport
+	Base http.RoundTripper
+}
+
+//RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.URL.Host != t.Host {
+		return base.RoundTrip(req)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := util.RandomStr(16)
+	req2 := req.Clone(req.Context())
+	req2.Header.Set(TimestampHeader, timestamp)
+	req2.Header.Set(NonceHeader, nonce)
+	query := req.URL.Query()
+	req2.Header.Set(SignatureHeader, Sign(t.Secret, req.Method, req.URL.Path, query.Get("appid"), query.Get("invalid_token"), timestamp, nonce))
+	return base.RoundTrip(req2)
+}
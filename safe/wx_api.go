@@ -1,6 +1,7 @@
 package safe
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
@@ -9,7 +10,6 @@ import (
 	"github.com/dcsunny/wechat/define"
 
 	"github.com/dcsunny/wechat/context"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -31,6 +31,11 @@ func NewWxSafe(context *context.Context) *WxSafe {
 	return tpl
 }
 
+//WithContext 返回绑定了ctx的WxSafe，其发起的请求受ctx的取消和超时控制
+func (s *WxSafe) WithContext(ctx stdcontext.Context) *WxSafe {
+	return NewWxSafe(s.Context.WithContext(ctx))
+}
+
 func (s *WxSafe) GetWxIp() (result IpListResult, err error) {
 	var accessToken string
 	accessToken, err = s.GetAccessToken()
@@ -39,7 +44,7 @@ func (s *WxSafe) GetWxIp() (result IpListResult, err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", getWxIpURL, accessToken)
 	var response []byte
-	response, err = util.HTTPGet(uri)
+	response, err = s.HTTPGet(uri)
 	if err != nil {
 		return
 	}
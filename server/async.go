@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/dcsunny/wechat/message"
+)
+
+const defaultAsyncBudget = 4 * time.Second
+
+//AsyncConfig 异步回复配置。微信5秒内未收到响应时会提示"该公众号暂时无法提供服务"，
+//开启后处理方法超过Budget仍未返回时先回复success，处理完成后通过客服消息接口发送回复
+type AsyncConfig struct {
+	//Budget 等待处理方法的时间，默认4秒
+	Budget time.Duration
+	//OnError 处理方法panic或客服消息发送失败时回调
+	OnError func(msg message.MixMessage, err error)
+}
+
+//SetAsyncReply 开启超时后异步回复，cfg为nil时关闭
+func (srv *Server) SetAsyncReply(cfg *AsyncConfig) {
+	if cfg == nil {
+		srv.async = nil
+		return
+	}
+	async := *cfg
+	if async.Budget <= 0 {
+		async.Budget = defaultAsyncBudget
+	}
+	srv.async = &async
+}
+
+//callHandler 调用消息处理方法，开启异步回复且超时时返回nil并标记asyncTimedOut
+func (srv *Server) callHandler(msg message.MixMessage) *message.Reply {
+	handler := Chain(srv.messageHandler, srv.middlewares...)
+	if srv.async == nil {
+		return handler(msg)
+	}
+
+	var mu sync.Mutex
+	timedOut := false
+	done := make(chan *message.Reply, 1)
+	go func() {
+		var reply *message.Reply
+		defer func() {
+			if e := recover(); e != nil {
+				srv.clearDedup()
+				srv.asyncError(msg, fmt.Errorf("panic error: %v\n%s", e, debug.Stack()))
+				reply = nil
+			}
+			mu.Lock()
+			late := timedOut
+			if !late {
+				done <- reply
+			}
+			mu.Unlock()
+			//已超时时请求已返回，在当前协程中发送，不再另起协程
+			if late {
+				srv.deliverAsync(msg, reply)
+			}
+		}()
+		reply = handler(msg)
+	}()
+
+	timer := time.NewTimer(srv.async.Budget)
+	defer timer.Stop()
+	select {
+	case reply := <-done:
+		return reply
+	case <-timer.C:
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	select {
+	case reply := <-done:
+		return reply
+	default:
+		timedOut = true
+		srv.asyncTimedOut = true
+		return nil
+	}
+}
+
+//deliverAsync 通过客服消息接口发送超时后得到的回复，在处理方法所在的协程中调用
+func (srv *Server) deliverAsync(msg message.MixMessage, reply *message.Reply) {
+	if reply == nil {
+		return
+	}
+	if text, ok := reply.MsgData.(*message.Text); ok && text.Content == "" {
+		return
+	}
+	customerMsg, err := message.NewCustomerMessageFromReply(string(msg.FromUserName), reply)
+	if err != nil {
+		srv.asyncError(msg, err)
+		return
+	}
+	if err := message.NewMessageManager(srv.Context).Send(customerMsg); err != nil {
+		srv.asyncError(msg, err)
+	}
+}
+
+func (srv *Server) asyncError(msg message.MixMessage, err error) {
+	if srv.async.OnError != nil {
+		srv.async.OnError(msg, err)
+	}
+}
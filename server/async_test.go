@@ -0,0 +1,57 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/message"
+)
+
+func TestServer_AsyncReply(t *testing.T) {
+	sent := make(chan message.CustomerMessage, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/cgi-bin/message/custom/send") {
+			var msg message.CustomerMessage
+			json.NewDecoder(r.Body).Decode(&msg)
+			sent <- msg
+		}
+		w.Write([]byte(`{"errcode":0,"access_token":"token","expires_in":7200}`))
+	}))
+	defer api.Close()
+
+	ctx := &context.Context{AppID: "appid", AppSecret: "secret", Token: testToken, Cache: cache.NewMemory(), APIHost: api.URL}
+	ctx.SetAccessTokenLock(new(sync.RWMutex))
+	srv := NewServer(ctx)
+	srv.SetAsyncReply(&AsyncConfig{Budget: 50 * time.Millisecond})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		if msg.Content == "slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("reply " + msg.Content)}
+	})
+	post := func(content string) string {
+		return postXML(srv, "<xml><ToUserName>gh_test</ToUserName><FromUserName>openid</FromUserName><CreateTime>1600000000</CreateTime><MsgType>text</MsgType><Content>"+content+"</Content><MsgId>1</MsgId></xml>")
+	}
+
+	if reply := post("fast"); !strings.Contains(reply, "reply fast") {
+		t.Errorf("expect passive reply, got %q", reply)
+	}
+	if reply := post("slow"); reply != "success" {
+		t.Errorf("expect success when handler is slow, got %q", reply)
+	}
+	select {
+	case msg := <-sent:
+		if msg.ToUser != "openid" || msg.Msgtype != message.MsgTypeText || msg.Text.Content != "reply slow" {
+			t.Errorf("unexpected customer message %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expect reply delivered by customer message")
+	}
+}
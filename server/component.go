@@ -0,0 +1,64 @@
+package server
+
+import (
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/message"
+)
+
+//ComponentHooks 第三方平台授权事件的回调，在SDK完成ticket、token的保存后调用；
+//返回错误时请求失败，微信会重新推送
+type ComponentHooks struct {
+	//OnVerifyTicket 收到component_verify_ticket
+	OnVerifyTicket func(msg message.MixMessage) error
+	//OnAuthorized 授权成功，info为使用授权码换取的授权信息
+	OnAuthorized func(msg message.MixMessage, info *context.AuthBaseInfo) error
+	//OnUpdateAuthorized 更新授权
+	OnUpdateAuthorized func(msg message.MixMessage, info *context.AuthBaseInfo) error
+	//OnUnauthorized 取消授权
+	OnUnauthorized func(msg message.MixMessage) error
+}
+
+//SetComponentHandler 处理第三方平台授权事件接收URL收到的推送，hooks为nil时关闭：
+//component_verify_ticket保存后获取component_access_token(未过期时不重新获取)；
+//authorized、updateauthorized使用授权码换取并保存授权方的token；unauthorized删除授权方的token。
+//处理后回复success，Server需使用第三方平台的Context
+func (srv *Server) SetComponentHandler(hooks *ComponentHooks) {
+	srv.component = hooks
+}
+
+//handleComponentEvent 处理第三方平台授权事件
+func (srv *Server) handleComponentEvent(msg message.MixMessage) error {
+	hooks := srv.component
+	switch msg.InfoType {
+	case message.InfoTypeVerifyTicket:
+		if err := srv.SetComponentVerifyTicket(msg.ComponentVerifyTicket); err != nil {
+			return err
+		}
+		if _, err := srv.GetComponentAccessToken(); err != nil {
+			return err
+		}
+		if hooks.OnVerifyTicket != nil {
+			return hooks.OnVerifyTicket(msg)
+		}
+	case message.InfoTypeAuthorized, message.InfoTypeUpdateAuthorized:
+		info, err := srv.QueryAuthCode(msg.AuthorizationCode)
+		if err != nil {
+			return err
+		}
+		hook := hooks.OnAuthorized
+		if msg.InfoType == message.InfoTypeUpdateAuthorized {
+			hook = hooks.OnUpdateAuthorized
+		}
+		if hook != nil {
+			return hook(msg, info)
+		}
+	case message.InfoTypeUnauthorized:
+		if err := srv.RemoveAuthorizer(msg.AuthorizerAppid); err != nil {
+			return err
+		}
+		if hooks.OnUnauthorized != nil {
+			return hooks.OnUnauthorized(msg)
+		}
+	}
+	return nil
+}
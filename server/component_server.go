@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	stdcontext "context"
+	"container/list"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/message"
+	"github.com/dcsunny/wechat/util"
+)
+
+const (
+	//AppIDPlaceholder 第三方平台消息与事件接收URL中代表授权方appid的占位符
+	AppIDPlaceholder = "$APPID$"
+
+	defaultServerCacheSize = 1000
+)
+
+//ErrUnknownAuthorizer 无法确定消息所属的授权方
+var ErrUnknownAuthorizer = errors.New("无法确定消息所属的授权方")
+
+//ComponentServer 第三方平台的消息与事件接收服务，实现了http.Handler。
+//按URL中$APPID$对应的路径或消息的ToUserName找到授权方，使用第三方平台的Token、EncodingAESKey校验及解密，
+//交给该授权方的Server处理，授权方的Server使用其authorizer_access_token调用接口
+type ComponentServer struct {
+	*context.Context
+
+	pathPattern     []string
+	resolveUserName func(userName string) (appid string, err error)
+	messageHandler  func(appid string, msg message.MixMessage) *message.Reply
+	setup           func(appid string, srv *Server)
+	errorHandler    func(w http.ResponseWriter, r *http.Request, err error)
+
+	releaseTest        bool
+	releaseTestOnError func(msg message.MixMessage, err error)
+	//releaseTestCtx 全网发布检测后台任务的父context，Shutdown时取消
+	releaseTestCtx    stdcontext.Context
+	releaseTestCancel stdcontext.CancelFunc
+	releaseTestWG     sync.WaitGroup
+	shutdown          bool
+
+	mu sync.Mutex
+	//servers 通过Authorizer获取的授权方Server，一直保留
+	servers map[string]*Server
+	//cached ServeHTTP自动创建的授权方Server，按最近使用排序，超过cacheSize时淘汰
+	cached    map[string]*list.Element
+	lru       *list.List
+	cacheSize int
+}
+
+type cachedServer struct {
+	appid string
+	srv   *Server
+}
+
+//NewComponentServer 使用第三方平台的Context创建消息与事件接收服务
+func NewComponentServer(ctx *context.Context) *ComponentServer {
+	return &ComponentServer{
+		Context:   ctx,
+		servers:   make(map[string]*Server),
+		cached:    make(map[string]*list.Element),
+		lru:       list.New(),
+		cacheSize: defaultServerCacheSize,
+	}
+}
+
+//SetPathPattern 设置消息与事件接收URL的路径，如"/wechat/$APPID$/callback"，从请求路径中对应的位置获取授权方appid
+func (cs *ComponentServer) SetPathPattern(pattern string) {
+	cs.pathPattern = strings.Split(strings.Trim(pattern, "/"), "/")
+}
+
+//SetUserNameResolver 设置通过授权方原始ID(消息的ToUserName)查找appid的方法，路径中没有appid时使用
+func (cs *ComponentServer) SetUserNameResolver(resolve func(userName string) (appid string, err error)) {
+	cs.resolveUserName = resolve
+}
+
+//SetMessageHandler 设置所有授权方共用的消息处理方法
+func (cs *ComponentServer) SetMessageHandler(handler func(appid string, msg message.MixMessage) *message.Reply) {
+	cs.messageHandler = handler
+}
+
+//SetServerSetup 设置授权方Server创建时的初始化方法，可为其设置路由、中间件等
+func (cs *ComponentServer) SetServerSetup(setup func(appid string, srv *Server)) {
+	cs.setup = setup
+}
+
+//SetErrorHandler 设置请求处理失败时的处理方法，默认返回400，同时应用于之后创建的授权方Server
+func (cs *ComponentServer) SetErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) {
+	cs.errorHandler = handler
+}
+
+//SetServerCacheSize 设置ServeHTTP自动创建的授权方Server最多缓存的数量，默认1000，超过时淘汰最久未使用的，
+//通过Authorizer获取的Server不受影响
+func (cs *ComponentServer) SetServerCacheSize(size int) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if size <= 0 {
+		size = defaultServerCacheSize
+	}
+	cs.cacheSize = size
+	cs.evict()
+}
+
+//Authorizer 返回处理授权方appid消息的Server，首次获取时创建并一直保留，可单独为其设置消息处理方法
+func (cs *ComponentServer) Authorizer(appid string) *Server {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if srv, ok := cs.servers[appid]; ok {
+		return srv
+	}
+	srv := cs.removeCached(appid)
+	if srv == nil {
+		srv = cs.newServer(appid)
+	}
+	cs.servers[appid] = srv
+	return srv
+}
+
+//serverFor 返回ServeHTTP处理appid消息的Server，未通过Authorizer获取时创建并放入有数量上限的缓存
+func (cs *ComponentServer) serverFor(appid string) *Server {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if srv, ok := cs.servers[appid]; ok {
+		return srv
+	}
+	if elem, ok := cs.cached[appid]; ok {
+		cs.lru.MoveToFront(elem)
+		return elem.Value.(*cachedServer).srv
+	}
+	srv := cs.newServer(appid)
+	cs.cached[appid] = cs.lru.PushFront(&cachedServer{appid: appid, srv: srv})
+	cs.evict()
+	return srv
+}
+
+func (cs *ComponentServer) evict() {
+	for cs.lru.Len() > cs.cacheSize {
+		cs.removeCached(cs.lru.Back().Value.(*cachedServer).appid)
+	}
+}
+
+func (cs *ComponentServer) removeCached(appid string) *Server {
+	elem, ok := cs.cached[appid]
+	if !ok {
+		return nil
+	}
+	cs.lru.Remove(elem)
+	delete(cs.cached, appid)
+	return elem.Value.(*cachedServer).srv
+}
+
+//newServer 创建授权方appid的Server，调用时需持有cs.mu
+func (cs *ComponentServer) newServer(appid string) *Server {
+	srv := NewServer(cs.AuthorizerContext(appid))
+	if cs.releaseTest && appid == ReleaseTestAppID {
+		srv.SetMessageHandler(cs.releaseTestHandler)
+	} else if cs.messageHandler != nil {
+		handler := cs.messageHandler
+		srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+			return handler(appid, msg)
+		})
+	}
+	if cs.errorHandler != nil {
+		srv.SetErrorHandler(cs.errorHandler)
+	}
+	if cs.setup != nil && !(cs.releaseTest && appid == ReleaseTestAppID) {
+		cs.setup(appid, srv)
+	}
+	return srv
+}
+
+//ServeHTTP 校验签名后将请求交给所属授权方的Server处理
+func (cs *ComponentServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var appid string
+	err := cs.checkSignature(r)
+	if err == nil {
+		appid, err = cs.resolveAppID(r)
+	}
+	if err != nil {
+		if cs.errorHandler != nil {
+			cs.errorHandler(w, r, err)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cs.serverFor(appid).ServeHTTP(w, r)
+}
+
+//checkSignature 在创建授权方Server前校验签名，避免未经校验的请求创建Server，
+//timestamp、nonce等其他校验仍由授权方的Server完成
+func (cs *ComponentServer) checkSignature(r *http.Request) error {
+	query := r.URL.Query()
+	timestamp, nonce, signature := query.Get("timestamp"), query.Get("nonce"), query.Get("signature")
+	if signature == "" || timestamp == "" || nonce == "" {
+		return ErrMissingSignature
+	}
+	if !equalSignature(signature, util.Signature(cs.Token, timestamp, nonce)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+//resolveAppID 从路径或消息的ToUserName获取授权方appid
+func (cs *ComponentServer) resolveAppID(r *http.Request) (string, error) {
+	if appid := cs.appIDFromPath(r.URL.Path); appid != "" {
+		return appid, nil
+	}
+	if cs.resolveUserName == nil && !cs.releaseTest {
+		return "", ErrUnknownAuthorizer
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取body失败, err=%v", err)
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	//安全模式下外层的ToUserName为明文
+	var envelope struct {
+		XMLName    struct{} `xml:"xml" json:"-"`
+		ToUserName string   `xml:"ToUserName" json:"ToUserName"`
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(trimmed, &envelope)
+	} else {
+		err = xml.Unmarshal(body, &envelope)
+	}
+	if err != nil {
+		return "", fmt.Errorf("从body中解析ToUserName失败, err=%v", err)
+	}
+	if envelope.ToUserName == "" {
+		return "", ErrUnknownAuthorizer
+	}
+	if cs.releaseTest && envelope.ToUserName == ReleaseTestUserName {
+		return ReleaseTestAppID, nil
+	}
+	if cs.resolveUserName == nil {
+		return "", ErrUnknownAuthorizer
+	}
+	appid, err := cs.resolveUserName(envelope.ToUserName)
+	if err != nil {
+		return "", err
+	}
+	if appid == "" {
+		return "", ErrUnknownAuthorizer
+	}
+	return appid, nil
+}
+
+func (cs *ComponentServer) appIDFromPath(path string) string {
+	if len(cs.pathPattern) == 0 {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(cs.pathPattern) {
+		return ""
+	}
+	var appid string
+	for i, segment := range cs.pathPattern {
+		switch {
+		case segment == AppIDPlaceholder:
+			appid = segments[i]
+		case segment != segments[i]:
+			return ""
+		}
+	}
+	return appid
+}
@@ -0,0 +1,303 @@
+package server_test
+
+import (
+	stdcontext "context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat"
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/message"
+	"github.com/dcsunny/wechat/server"
+	"github.com/dcsunny/wechat/wechattest"
+)
+
+func TestComponentHandler(t *testing.T) {
+	const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	s := wechattest.NewServer()
+	defer s.Close()
+	wc := s.NewWechat(&wechat.Config{AppID: "component_appid", AppSecret: "component_secret", Token: "token", EncodingAESKey: aesKey, Cache: cache.NewMemory()})
+
+	var events []string
+	srv := wc.NewServer()
+	srv.SetComponentHandler(&server.ComponentHooks{
+		OnVerifyTicket: func(msg message.MixMessage) error {
+			events = append(events, "ticket:"+msg.ComponentVerifyTicket)
+			return nil
+		},
+		OnAuthorized: func(msg message.MixMessage, info *context.AuthBaseInfo) error {
+			events = append(events, "authorized:"+info.Appid)
+			return nil
+		},
+		OnUnauthorized: func(msg message.MixMessage) error {
+			events = append(events, "unauthorized:"+msg.AuthorizerAppid)
+			return nil
+		},
+	})
+	c := &wechattest.Callback{Token: "token", AppID: "component_appid", EncodingAESKey: aesKey}
+	push := func(body string) {
+		t.Helper()
+		reply, err := c.Do(srv, "<xml><AppId>component_appid</AppId><CreateTime>1600000000</CreateTime>"+body+"</xml>")
+		if err != nil || string(reply.Body) != "success" {
+			t.Fatalf("expect success, got %v %v", reply, err)
+		}
+	}
+
+	push("<InfoType>component_verify_ticket</InfoType><ComponentVerifyTicket>ticket@@@</ComponentVerifyTicket>")
+	if token, err := wc.Context.GetComponentAccessToken(); err != nil || token != wechattest.ComponentAccessToken {
+		t.Errorf("expect component_access_token fetched with pushed ticket, got %q %v", token, err)
+	}
+
+	push("<InfoType>authorized</InfoType><AuthorizerAppid>" + wechattest.AuthorizerAppID + "</AuthorizerAppid><AuthorizationCode>code</AuthorizationCode>")
+	if _, err := wc.Context.GetAuthrAccessToken(wechattest.AuthorizerAppID); err != nil {
+		t.Errorf("expect authorizer token saved, got %v", err)
+	}
+
+	push("<InfoType>unauthorized</InfoType><AuthorizerAppid>" + wechattest.AuthorizerAppID + "</AuthorizerAppid>")
+	if _, err := wc.Context.GetAuthrAccessToken(wechattest.AuthorizerAppID); err == nil {
+		t.Error("expect authorizer token removed")
+	}
+
+	if got := strings.Join(events, ","); got != "ticket:ticket@@@,authorized:"+wechattest.AuthorizerAppID+",unauthorized:"+wechattest.AuthorizerAppID {
+		t.Errorf("unexpected hooks %s", got)
+	}
+}
+
+func TestComponentServer(t *testing.T) {
+	const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	s := wechattest.NewServer()
+	defer s.Close()
+	wc := s.NewWechat(&wechat.Config{AppID: "component_appid", AppSecret: "component_secret", Token: "token", EncodingAESKey: aesKey, Cache: cache.NewMemory()})
+
+	cs := wc.NewComponentServer()
+	cs.SetPathPattern("/wechat/$APPID$/callback")
+	cs.SetUserNameResolver(func(userName string) (string, error) {
+		if userName == "gh_authorizer" {
+			return "wxresolved", nil
+		}
+		return "", nil
+	})
+	cs.SetMessageHandler(func(appid string, msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("shared " + appid)}
+	})
+	cs.Authorizer("wxdedicated").SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("dedicated")}
+	})
+	ts := httptest.NewServer(cs)
+	defer ts.Close()
+
+	c := &wechattest.Callback{Token: "token", AppID: "component_appid", EncodingAESKey: aesKey}
+	tests := []struct {
+		path, toUserName, want string
+	}{
+		{"/wechat/wxpath/callback", "gh_other", "shared wxpath"},
+		{"/wechat/wxdedicated/callback", "gh_other", "dedicated"},
+		{"/other", "gh_authorizer", "shared wxresolved"},
+	}
+	for _, tt := range tests {
+		c.ToUserName = tt.toUserName
+		reply, err := c.Post(ts.URL+tt.path, wechattest.TextMessage("openid", "hi"))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if text, err := reply.Text(); err != nil || text != tt.want {
+			t.Errorf("%s: expect %q, got %q %v", tt.path, tt.want, text, err)
+		}
+	}
+
+	c.ToUserName = "gh_unknown"
+	if _, err := c.Post(ts.URL+"/other", wechattest.TextMessage("openid", "hi")); err == nil {
+		t.Error("expect error for unknown authorizer")
+	}
+	if appid := cs.Authorizer("wxpath").ComponentAppID(); appid != "component_appid" {
+		t.Errorf("expect authorizer server acting for component, got %q", appid)
+	}
+}
+
+func TestComponentReleaseTest(t *testing.T) {
+	const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	s := wechattest.NewServer()
+	defer s.Close()
+	wc := s.NewWechat(&wechat.Config{AppID: "component_appid", AppSecret: "component_secret", Token: "token", EncodingAESKey: aesKey, Cache: cache.NewMemory()})
+	if err := wc.Context.SetComponentVerifyTicket("ticket"); err != nil {
+		t.Fatal(err)
+	}
+
+	cs := wc.NewComponentServer()
+	cs.SetUserNameResolver(func(userName string) (string, error) {
+		return "", nil
+	})
+	cs.SetMessageHandler(func(appid string, msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("shared")}
+	})
+	errs := make(chan error, 1)
+	cs.EnableReleaseTest(func(msg message.MixMessage, err error) {
+		errs <- err
+	})
+	ts := httptest.NewServer(cs)
+	defer ts.Close()
+
+	c := &wechattest.Callback{Token: "token", AppID: "component_appid", EncodingAESKey: aesKey, ToUserName: server.ReleaseTestUserName}
+	reply, err := c.Post(ts.URL, wechattest.TextMessage("openid", "TESTCOMPONENT_MSG_TYPE_TEXT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, err := reply.Text(); err != nil || text != "TESTCOMPONENT_MSG_TYPE_TEXT_callback" {
+		t.Errorf("unexpected text reply %q %v", text, err)
+	}
+	reply, err = c.Post(ts.URL, wechattest.EventMessage("openid", message.EventLocation, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, err := reply.Text(); err != nil || text != "LOCATIONfrom_callback" {
+		t.Errorf("unexpected event reply %q %v", text, err)
+	}
+
+	reply, err = c.Post(ts.URL, wechattest.TextMessage("openid", "QUERY_AUTH_CODE:authcode"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.MsgType != "" || strings.TrimSpace(string(reply.Body)) != "" {
+		t.Errorf("expect empty reply, got %q", reply.Body)
+	}
+	if err := cs.Shutdown(stdcontext.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	default:
+	}
+	sends := s.Requests("/cgi-bin/message/custom/send")
+	if len(sends) != 1 {
+		t.Fatalf("expect 1 customer message, got %d", len(sends))
+	}
+	if body := string(sends[0].Body); !strings.Contains(body, "authcode_from_api") || !strings.Contains(body, `"touser":"openid"`) {
+		t.Errorf("unexpected customer message %s", body)
+	}
+	if token := sends[0].Query.Get("access_token"); token != s.AccessToken() {
+		t.Errorf("expect authorizer access_token, got %q", token)
+	}
+	if len(s.Requests("/cgi-bin/component/api_query_auth")) != 1 {
+		t.Error("expect query auth code")
+	}
+}
+
+func TestComponentReleaseTestShutdown(t *testing.T) {
+	const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	s := wechattest.NewServer()
+	defer s.Close()
+	wc := s.NewWechat(&wechat.Config{AppID: "component_appid", AppSecret: "component_secret", Token: "token", EncodingAESKey: aesKey, Cache: cache.NewMemory()})
+	if err := wc.Context.SetComponentVerifyTicket("ticket"); err != nil {
+		t.Fatal(err)
+	}
+	//客服消息接口一直不返回，直到请求被取消
+	s.Handle("/cgi-bin/message/custom/send", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	cs := wc.NewComponentServer()
+	errs := make(chan error, 1)
+	cs.EnableReleaseTest(func(msg message.MixMessage, err error) {
+		errs <- err
+	})
+	ts := httptest.NewServer(cs)
+	defer ts.Close()
+
+	c := &wechattest.Callback{Token: "token", AppID: "component_appid", EncodingAESKey: aesKey, ToUserName: server.ReleaseTestUserName}
+	if _, err := c.Post(ts.URL, wechattest.TextMessage("openid", "QUERY_AUTH_CODE:authcode")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := cs.Shutdown(ctx); err != stdcontext.DeadlineExceeded {
+		t.Fatalf("expect DeadlineExceeded, got %v", err)
+	}
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expect error for cancelled customer message")
+		}
+	default:
+		t.Error("expect onError after Shutdown returns")
+	}
+
+	//Shutdown后不再启动新的任务
+	if _, err := c.Post(ts.URL, wechattest.TextMessage("openid", "QUERY_AUTH_CODE:authcode2")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Requests("/cgi-bin/component/api_query_auth")); n != 1 {
+		t.Errorf("expect 1 query auth code after shutdown, got %d", n)
+	}
+
+	//Shutdown后重新开启，使用新的context
+	s.Reset("/cgi-bin/message/custom/send")
+	cs.EnableReleaseTest(func(msg message.MixMessage, err error) {
+		errs <- err
+	})
+	if _, err := c.Post(ts.URL, wechattest.TextMessage("openid", "QUERY_AUTH_CODE:authcode3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.Shutdown(stdcontext.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		t.Errorf("expect release test enabled again after Shutdown, got %v", err)
+	default:
+	}
+	sends := s.Requests("/cgi-bin/message/custom/send")
+	if len(sends) != 2 || !strings.Contains(string(sends[1].Body), "authcode3_from_api") {
+		t.Errorf("expect customer message after re-enabling, got %d", len(sends))
+	}
+}
+
+func TestComponentServerCache(t *testing.T) {
+	s := wechattest.NewServer()
+	defer s.Close()
+	wc := s.NewWechat(&wechat.Config{AppID: "component_appid", AppSecret: "component_secret", Token: "token", Cache: cache.NewMemory()})
+
+	cs := wc.NewComponentServer()
+	cs.SetPathPattern("/wechat/$APPID$/callback")
+	cs.SetServerCacheSize(1)
+	var created []string
+	cs.SetServerSetup(func(appid string, srv *server.Server) {
+		created = append(created, appid)
+	})
+	cs.SetMessageHandler(func(appid string, msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(appid)}
+	})
+	ts := httptest.NewServer(cs)
+	defer ts.Close()
+
+	//未通过签名校验的请求不创建Server
+	forged := &wechattest.Callback{Token: "forged"}
+	for _, appid := range []string{"wxrandom1", "wxrandom2"} {
+		if reply, err := forged.Post(ts.URL+"/wechat/"+appid+"/callback", wechattest.TextMessage("openid", "hi")); err == nil || reply.StatusCode != http.StatusBadRequest {
+			t.Errorf("expect forged request rejected, got %v", err)
+		}
+	}
+	if len(created) != 0 {
+		t.Fatalf("expect no server created for unsigned requests, got %v", created)
+	}
+
+	c := &wechattest.Callback{Token: "token"}
+	cs.Authorizer("wxpinned")
+	for _, appid := range []string{"wxa", "wxb", "wxa", "wxpinned", "wxpinned"} {
+		reply, err := c.Post(ts.URL+"/wechat/"+appid+"/callback", wechattest.TextMessage("openid", "hi"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text, _ := reply.Text(); text != appid {
+			t.Errorf("expect reply from %s, got %q", appid, text)
+		}
+	}
+	//缓存数量为1，wxa被wxb淘汰后重新创建，通过Authorizer获取的wxpinned一直保留
+	if got := strings.Join(created, ","); got != "wxpinned,wxa,wxb,wxa" {
+		t.Errorf("unexpected servers created: %s", got)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/message"
+)
+
+const (
+	defaultDedupExpiration = 5 * time.Minute
+	dedupCacheKey          = "wechat_msg_dedup:%s:%s"
+	//dedupNoReply 已处理但没有回复(或不重放回复)时缓存的值
+	dedupNoReply = "-"
+)
+
+//DedupConfig 重复消息过滤配置。微信在5秒内未收到响应时会重试3次，
+//开启后同一条消息(普通消息按MsgID，事件按FromUserName+CreateTime+Event)只会被处理一次。
+//处理方法panic或生成回复失败时删除标记，允许微信重试；进程在处理过程中退出时标记会保留到Expiration
+type DedupConfig struct {
+	//Cache 保存已处理的消息，为空时使用Context.Cache。实现了cache.Locker(内置的Memory、Redis、Memcache均已实现)时
+	//使用其原子地标记消息；未实现时先判断再写入，并发到达的重试可能都被处理
+	Cache cache.Cache
+	//Expiration 记录保留的时间，默认5分钟
+	Expiration time.Duration
+	//ReplayReply 为true时对重复的消息回复首次处理时的回复，否则回复空串
+	ReplayReply bool
+}
+
+//rawReply 重放缓存的回复
+type rawReply []byte
+
+//SetDedup 开启重复消息过滤，cfg为nil时关闭
+func (srv *Server) SetDedup(cfg *DedupConfig) {
+	if cfg == nil {
+		srv.dedup = nil
+		return
+	}
+	dedup := *cfg
+	if dedup.Expiration <= 0 {
+		dedup.Expiration = defaultDedupExpiration
+	}
+	srv.dedup = &dedup
+}
+
+//DedupKey 返回用于判断消息是否重复的key
+func DedupKey(msg message.MixMessage) string {
+	if msg.MsgID != 0 {
+		return fmt.Sprintf("%d", msg.MsgID)
+	}
+	return fmt.Sprintf("%s:%d:%s", msg.FromUserName, msg.CreateTime, msg.Event)
+}
+
+func (srv *Server) dedupCache() cache.Cache {
+	if srv.dedup.Cache != nil {
+		return srv.dedup.Cache
+	}
+	return srv.Cache
+}
+
+//checkDuplicate 判断消息是否已处理过，未处理时将其标记为已处理，Cache未实现cache.Locker时判断和标记不是原子的；
+//重复时返回首次处理的回复(未开启ReplayReply、首次处理尚未完成或没有回复时为空)
+func (srv *Server) checkDuplicate(msg message.MixMessage) (duplicate bool, reply []byte, err error) {
+	c := srv.dedupCache()
+	srv.dedupKey = fmt.Sprintf(dedupCacheKey, srv.AppID, DedupKey(msg))
+	if locker, ok := c.(cache.Locker); ok {
+		var acquired bool
+		acquired, err = locker.Acquire(srv.dedupKey, dedupNoReply, srv.dedup.Expiration)
+		if err != nil || acquired {
+			return
+		}
+	} else if !c.IsExist(srv.dedupKey) {
+		err = c.SetString(srv.dedupKey, dedupNoReply, srv.dedup.Expiration)
+		return
+	}
+
+	duplicate = true
+	if cached := c.GetString(srv.dedupKey); srv.dedup.ReplayReply && cached != dedupNoReply {
+		reply = []byte(cached)
+	}
+	return
+}
+
+//clearDedup 处理失败时删除消息的标记，允许微信重试
+func (srv *Server) clearDedup() {
+	if srv.dedup != nil && srv.dedupKey != "" {
+		srv.dedupCache().Delete(srv.dedupKey)
+	}
+}
+
+//saveReply 保存首次处理的回复，用于重放
+func (srv *Server) saveReply() error {
+	if srv.dedup == nil || !srv.dedup.ReplayReply || srv.dedupKey == "" || len(srv.responseRawXMLMsg) == 0 {
+		return nil
+	}
+	return srv.dedupCache().SetString(srv.dedupKey, string(srv.responseRawXMLMsg), srv.dedup.Expiration)
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/message"
+)
+
+func TestServer_Dedup(t *testing.T) {
+	var handled int
+	srv := NewServer(&context.Context{Token: testToken, Cache: cache.NewMemory()})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		handled++
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("coupon")}
+	})
+	post := func(body string) string {
+		return postXML(srv, body)
+	}
+	event := "<xml><ToUserName>gh_test</ToUserName><FromUserName>openid</FromUserName><CreateTime>1600000000</CreateTime><MsgType>event</MsgType><Event>subscribe</Event></xml>"
+
+	srv.SetDedup(&DedupConfig{})
+	post(event)
+	if reply := post(event); reply != "" || handled != 1 {
+		t.Errorf("expect retried event skipped with empty reply, got %q handled=%d", reply, handled)
+	}
+
+	srv.SetDedup(&DedupConfig{ReplayReply: true})
+	text := "<xml><ToUserName>gh_test</ToUserName><FromUserName>openid</FromUserName><CreateTime>1600000000</CreateTime><MsgType>text</MsgType><Content>hi</Content><MsgId>1234</MsgId></xml>"
+	first := post(text)
+	retry := post(text)
+	if handled != 2 {
+		t.Errorf("expect message handled once, handled=%d", handled)
+	}
+	var reply message.Text
+	if err := xml.Unmarshal([]byte(retry), &reply); err != nil || retry != first || reply.Content != "coupon" {
+		t.Errorf("expect replayed reply %q, got %q", first, retry)
+	}
+}
+
+func TestServer_DedupHandlerPanic(t *testing.T) {
+	var handled int
+	srv := NewServer(&context.Context{Token: testToken, Cache: cache.NewMemory()})
+	srv.SetDedup(&DedupConfig{})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		handled++
+		if handled == 1 {
+			panic("handler failed")
+		}
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("ok")}
+	})
+	text := "<xml><ToUserName>gh_test</ToUserName><FromUserName>openid</FromUserName><CreateTime>1600000000</CreateTime><MsgType>text</MsgType><Content>hi</Content><MsgId>5678</MsgId></xml>"
+
+	func() {
+		defer func() {
+			if e := recover(); e == nil {
+				t.Error("expect handler panic propagated")
+			}
+		}()
+		postXML(srv, text)
+	}()
+	//panic后微信的重试需要重新处理
+	if reply := postXML(srv, text); !strings.Contains(reply, "ok") || handled != 2 {
+		t.Errorf("expect retry handled after panic, got %q handled=%d", reply, handled)
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+)
+
+//DataType 消息推送的数据格式，小程序可在后台配置为JSON
+type DataType string
+
+const (
+	//DataTypeAuto 根据请求的Content-Type及消息体判断，默认值
+	DataTypeAuto DataType = ""
+	//DataTypeXML XML格式
+	DataTypeXML DataType = "xml"
+	//DataTypeJSON JSON格式
+	DataTypeJSON DataType = "json"
+)
+
+//SetDataType 设置消息推送的数据格式，回复消息使用相同的格式
+func (srv *Server) SetDataType(dataType DataType) {
+	srv.dataType = dataType
+}
+
+//detectJSON 判断请求消息是否为JSON格式
+func (srv *Server) detectJSON(body []byte) bool {
+	switch srv.dataType {
+	case DataTypeJSON:
+		return true
+	case DataTypeXML:
+		return false
+	}
+	contentType := strings.ToLower(srv.Request.Header.Get("Content-Type"))
+	if strings.Contains(contentType, "json") {
+		return true
+	}
+	if strings.Contains(contentType, "xml") {
+		return false
+	}
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && body[0] == '{'
+}
+
+func (srv *Server) unmarshal(data []byte, v interface{}) error {
+	if srv.isJSON {
+		return json.Unmarshal(data, v)
+	}
+	return xml.Unmarshal(data, v)
+}
+
+func (srv *Server) marshal(v interface{}) ([]byte, error) {
+	if srv.isJSON {
+		return json.Marshal(v)
+	}
+	return xml.Marshal(v)
+}
+
+func (srv *Server) format() string {
+	if srv.isJSON {
+		return "json"
+	}
+	return "xml"
+}
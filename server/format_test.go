@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/message"
+	"github.com/dcsunny/wechat/util"
+)
+
+func TestServer_JSON(t *testing.T) {
+	const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	var received message.MixMessage
+	srv := NewServer(&context.Context{AppID: "wxappid", Token: testToken, EncodingAESKey: aesKey})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		received = msg
+		return &message.Reply{MsgType: message.MsgTypeTransfer, MsgData: message.NewTransferCustomer("")}
+	})
+	post := func(query, contentType, body string) (string, string) {
+		w := postSigned(srv, query, contentType, body)
+		return w.Body.String(), w.Header().Get("Content-Type")
+	}
+	event := `{"ToUserName":"gh_test","FromUserName":"openid","CreateTime":1600000000,"MsgType":"event","Event":"wxa_media_check","isrisky":1,"extra_info_json":"","appid":"wxappid","trace_id":"trace","status_code":0}`
+
+	reply, contentType := post(signQuery(""), "application/json", event)
+	if !received.IsRisky || received.Event != "wxa_media_check" || received.AppID != "wxappid" || received.TraceID != "trace" {
+		t.Errorf("unexpected message %+v", received)
+	}
+	var transfer map[string]interface{}
+	if err := json.Unmarshal([]byte(reply), &transfer); err != nil || transfer["MsgType"] != "transfer_customer_service" || transfer["ToUserName"] != "openid" {
+		t.Errorf("expect json reply, got %q", reply)
+	}
+	if !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("expect json content type, got %q", contentType)
+	}
+
+	encrypted, err := util.EncryptMsg([]byte("0123456789abcdef"), []byte(`{"ToUserName":"gh_test","FromUserName":"openid","CreateTime":1600000000,"MsgType":"text","Content":"hello","MsgId":1}`), "wxappid", aesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetDataType(DataTypeJSON)
+	body, _ := json.Marshal(message.EncryptedXMLMsg{ToUserName: "gh_test", EncryptedMsg: string(encrypted)})
+	reply, _ = post(signQuery(string(encrypted)), "text/plain", string(body))
+	if received.Content != "hello" || received.MsgID != 1 {
+		t.Errorf("unexpected message %+v", received)
+	}
+	var resp message.ResponseEncryptedXMLMsg
+	if err := json.Unmarshal([]byte(reply), &resp); err != nil {
+		t.Fatalf("expect encrypted json reply, got %q", reply)
+	}
+	_, raw, err := util.DecryptMsg("wxappid", resp.EncryptedMsg, aesKey)
+	if err != nil || !strings.Contains(string(raw), `"MsgType":"transfer_customer_service"`) {
+		t.Errorf("unexpected decrypted reply %s, err=%v", raw, err)
+	}
+}
+
+func TestServer_JSONReplyTypes(t *testing.T) {
+	var reply *message.Reply
+	srv := NewServer(&context.Context{AppID: "wxappid", Token: testToken})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		return reply
+	})
+	event := `{"ToUserName":"gh_test","FromUserName":"openid","CreateTime":1600000000,"MsgType":"text","Content":"hi","MsgId":1}`
+
+	for _, c := range []struct {
+		reply  *message.Reply
+		expect string
+	}{
+		{&message.Reply{MsgType: message.MsgTypeImage, MsgData: message.NewImage("media")}, `"Image":{"MediaId":"media"}`},
+		{&message.Reply{MsgType: message.MsgTypeVoice, MsgData: message.NewVoice("media")}, `"Voice":{"MediaId":"media"}`},
+		{&message.Reply{MsgType: message.MsgTypeVideo, MsgData: message.NewVideo("media", "title", "")}, `"Video":{"MediaId":"media","Title":"title"}`},
+		{&message.Reply{MsgType: message.MsgTypeMusic, MsgData: message.NewMusic("title", "", "url", "", "thumb")}, `"MusicUrl":"url"`},
+		{&message.Reply{MsgType: message.MsgTypeNews, MsgData: message.NewNews([]*message.Article{message.NewArticle("title", "", "pic", "url")})}, `"ArticleCount":1,"Articles":[{"Title":"title","PicUrl":"pic","Url":"url"}]`},
+	} {
+		reply = c.reply
+		body := postSigned(srv, signQuery(""), "application/json", event).Body.String()
+		if !strings.Contains(body, c.expect) || !strings.Contains(body, `"ToUserName":"openid"`) {
+			t.Errorf("%s: expect %s, got %s", c.reply.MsgType, c.expect, body)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package server
+
+import (
+	"bytes"
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dcsunny/wechat/message"
+	"github.com/dcsunny/wechat/util"
+)
+
+const (
+	defaultForwardTimeout = 4500 * time.Millisecond
+	//defaultForwardDeadline 微信只等待5秒，从收到请求起包括重试在内的转发总时长默认不超过4.5秒
+	defaultForwardDeadline = 4500 * time.Millisecond
+	//legacyForwardFallback SetMessageForward上游超时时回复的文本
+	legacyForwardFallback = "系统异常,请稍后再试"
+)
+
+//Upstream 接收转发消息的上游服务，上游按明文模式接收消息，回复会按当前请求的模式加密后返回给微信
+type Upstream struct {
+	//Name 用于错误信息中区分上游
+	Name string
+	URL  string
+	//Token 为上游重新生成signature使用的token，为空时使用Context.Token
+	Token string
+	//Timeout 单次请求的超时时间，默认4.5秒
+	Timeout time.Duration
+	//Client 为空时使用http.DefaultClient
+	Client *http.Client
+
+	//presigned URL中已带有timestamp、nonce、signature，不再重新生成（MessageForwardSend）
+	presigned bool
+}
+
+//ForwardRule 转发规则，设置的条件都满足时匹配
+type ForwardRule struct {
+	MsgType        message.MsgType
+	Event          message.EventType
+	EventKeyPrefix string
+	//Match 自定义条件
+	Match func(msg message.MixMessage) bool
+	//Upstreams 匹配时转发的上游，有多个时按FromUserName(openid)哈希选择，同一用户固定转发到同一个上游
+	Upstreams []*Upstream
+}
+
+//RetryPolicy 转发失败时的重试策略，连接失败及上游返回5xx时重试
+type RetryPolicy struct {
+	//MaxRetries 最大重试次数，默认不重试
+	MaxRetries int
+	//Interval 重试间隔
+	Interval time.Duration
+	//RetryOnTimeout 为true时上游超时也重试，注意微信只等待5秒
+	RetryOnTimeout bool
+}
+
+//ForwardConfig 消息转发配置，消息处理方法返回nil时按规则将消息转发到上游，并将上游的回复返回给微信
+type ForwardConfig struct {
+	//Rules 按顺序匹配，使用第一个匹配的规则
+	Rules []*ForwardRule
+	//Default 没有匹配的规则时转发的上游，为空时不转发
+	Default []*Upstream
+	Retry   RetryPolicy
+	//Deadline 从收到请求起转发（包括重试）的总时长上限，默认4.5秒，超过后不再重试并按转发失败处理
+	Deadline time.Duration
+	//FallbackText 转发失败时回复的文本，为空时回复空串
+	FallbackText string
+	//OnError 转发失败时回调，err为*ForwardError
+	OnError func(msg message.MixMessage, err error)
+}
+
+//ForwardError 转发失败的错误
+type ForwardError struct {
+	Upstream string
+	URL      string
+	//Attempts 请求的次数
+	Attempts int
+	//StatusCode 上游返回的http状态码，请求失败时为0
+	StatusCode int
+	Err        error
+}
+
+func (e *ForwardError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("forward to %s(%s) failed after %d attempts: %v", e.Upstream, e.URL, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("forward to %s(%s) failed after %d attempts: http status %d", e.Upstream, e.URL, e.Attempts, e.StatusCode)
+}
+
+func (e *ForwardError) Unwrap() error {
+	return e.Err
+}
+
+//Timeout 是否因上游超时失败
+func (e *ForwardError) Timeout() bool {
+	return isTimeout(e.Err)
+}
+
+//SetForward 设置消息转发，cfg为nil时关闭
+func (srv *Server) SetForward(cfg *ForwardConfig) {
+	if cfg == nil {
+		srv.forward = nil
+		return
+	}
+	forward := *cfg
+	srv.forward = &forward
+}
+
+//SetMessageForward 将消息处理方法没有回复的消息转发到url，使用token为上游生成签名，超时时回复"系统异常,请稍后再试"
+func (srv *Server) SetMessageForward(url string, token string) {
+	if url == "" {
+		srv.SetForward(nil)
+		return
+	}
+	srv.SetForward(&ForwardConfig{
+		Default:      []*Upstream{{Name: "default", URL: url, Token: token}},
+		Retry:        RetryPolicy{MaxRetries: 2},
+		FallbackText: legacyForwardFallback,
+	})
+}
+
+//MessageForwardSend 将消息转发到postUrl并回复上游的回复，postUrl需已带有timestamp、nonce、signature参数，
+//连接失败时最多重试2次，retryNum为已重试的次数
+//
+//Deprecated: 使用SetMessageForward或SetForward
+func (srv *Server) MessageForwardSend(postUrl string, retryNum *int) {
+	retries := 2
+	if retryNum != nil {
+		retries -= *retryNum
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	forward := srv.forward
+	defer func() {
+		srv.forward = forward
+	}()
+	srv.forward = &ForwardConfig{
+		Default:      []*Upstream{{Name: "default", URL: postUrl, presigned: true}},
+		Retry:        RetryPolicy{MaxRetries: retries},
+		FallbackText: legacyForwardFallback,
+	}
+	srv.MessageForward()
+}
+
+//match 判断消息是否满足规则
+func (rule *ForwardRule) match(msg message.MixMessage) bool {
+	if rule.MsgType != "" && rule.MsgType != msg.MsgType {
+		return false
+	}
+	if rule.Event != "" && rule.Event != msg.Event {
+		return false
+	}
+	if rule.EventKeyPrefix != "" && !strings.HasPrefix(msg.EventKey, rule.EventKeyPrefix) {
+		return false
+	}
+	return rule.Match == nil || rule.Match(msg)
+}
+
+//selectUpstream 返回msg需要转发的上游，不需要转发时返回nil
+func (cfg *ForwardConfig) selectUpstream(msg message.MixMessage) *Upstream {
+	upstreams := cfg.Default
+	for _, rule := range cfg.Rules {
+		if rule.match(msg) {
+			upstreams = rule.Upstreams
+			break
+		}
+	}
+	switch len(upstreams) {
+	case 0:
+		return nil
+	case 1:
+		return upstreams[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(msg.FromUserName))
+	return upstreams[h.Sum32()%uint32(len(upstreams))]
+}
+
+//MessageForward 将消息转发到匹配的上游，并回复上游的回复
+func (srv *Server) MessageForward() {
+	if srv.forward == nil {
+		return
+	}
+	upstream := srv.forward.selectUpstream(srv.requestMsg)
+	if upstream == nil {
+		return
+	}
+	body, err := srv.forwardTo(upstream)
+	if err != nil {
+		if srv.forward.OnError != nil {
+			srv.forward.OnError(srv.requestMsg, err)
+		}
+		if srv.forward.FallbackText != "" {
+			srv.sendForwardFallback()
+		}
+		return
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || string(trimmed) == "success" {
+		srv.String(string(trimmed))
+		return
+	}
+	srv.responseRawXMLMsg = body
+	replyMsg, err := srv.sendBuildMsg(rawReply(body))
+	if err != nil {
+		if srv.forward.OnError != nil {
+			srv.forward.OnError(srv.requestMsg, err)
+		}
+		return
+	}
+	srv.writeReply(replyMsg)
+}
+
+func (srv *Server) sendForwardFallback() {
+	reply := &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(srv.forward.FallbackText)}
+	if err := srv.buildResponse(reply); err != nil {
+		return
+	}
+	if replyMsg, err := srv.sendBuildMsg(srv.responseMsg); err == nil {
+		srv.writeReply(replyMsg)
+	}
+}
+
+//forwardTo 按重试策略将明文消息转发到upstream，返回上游的回复；从收到请求起超过Deadline后不再重试
+func (srv *Server) forwardTo(upstream *Upstream) ([]byte, error) {
+	policy := srv.forward.Retry
+	deadline := srv.forward.Deadline
+	if deadline <= 0 {
+		deadline = defaultForwardDeadline
+	}
+	started := srv.started
+	if started.IsZero() {
+		started = time.Now()
+	}
+	ctx, cancel := stdcontext.WithDeadline(srv.Request.Context(), started.Add(deadline))
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		body, statusCode, err := srv.postUpstream(ctx, upstream)
+		if err == nil && statusCode == http.StatusOK {
+			return body, nil
+		}
+		retryable := statusCode >= http.StatusInternalServerError
+		if err != nil {
+			retryable = !isTimeout(err) || policy.RetryOnTimeout
+		}
+		if ctx.Err() != nil {
+			//总时限已到，重试也无法在微信等待的时间内回复
+			retryable = false
+			if err == nil {
+				err = ctx.Err()
+			}
+		}
+		if attempt > policy.MaxRetries || !retryable {
+			return nil, &ForwardError{Upstream: upstream.Name, URL: upstream.URL, Attempts: attempt, StatusCode: statusCode, Err: err}
+		}
+		if policy.Interval > 0 {
+			timer := time.NewTimer(policy.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, &ForwardError{Upstream: upstream.Name, URL: upstream.URL, Attempts: attempt, StatusCode: statusCode, Err: ctx.Err()}
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func (srv *Server) postUpstream(parent stdcontext.Context, upstream *Upstream) ([]byte, int, error) {
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		return nil, 0, err
+	}
+	token := upstream.Token
+	if token == "" {
+		token = srv.Token
+	}
+	query := u.Query()
+	if !upstream.presigned {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		nonce := util.RandomStr(10)
+		query.Set("timestamp", timestamp)
+		query.Set("nonce", nonce)
+		query.Set("signature", util.Signature(token, timestamp, nonce))
+	}
+	if openID := string(srv.requestMsg.FromUserName); openID != "" {
+		query.Set("openid", openID)
+	}
+	u.RawQuery = query.Encode()
+
+	timeout := upstream.Timeout
+	if timeout <= 0 {
+		timeout = defaultForwardTimeout
+	}
+	ctx, cancel := stdcontext.WithTimeout(parent, timeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(srv.requestRawXMLMsg))
+	if err != nil {
+		return nil, 0, err
+	}
+	req = req.WithContext(ctx)
+	if srv.isJSON {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "text/xml")
+	}
+	client := upstream.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return body, resp.StatusCode, nil
+}
+
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, stdcontext.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/message"
+	"github.com/dcsunny/wechat/util"
+)
+
+func newUpstream(t *testing.T, token, reply string, status int, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		query := r.URL.Query()
+		if query.Get("signature") != util.Signature(token, query.Get("timestamp"), query.Get("nonce")) {
+			t.Errorf("upstream received invalid signature")
+		}
+		if body, _ := ioutil.ReadAll(r.Body); !strings.Contains(string(body), "<FromUserName>openid</FromUserName>") {
+			t.Errorf("upstream received unexpected body %s", body)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(reply))
+	}))
+}
+
+func TestServer_Forward(t *testing.T) {
+	var promoHits, defaultHits, brokenHits int32
+	promo := newUpstream(t, "promo-token", "<xml><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[promo]]></Content></xml>", http.StatusOK, &promoHits)
+	defer promo.Close()
+	fallback := newUpstream(t, "token", "success", http.StatusOK, &defaultHits)
+	defer fallback.Close()
+	broken := newUpstream(t, "token", "", http.StatusBadGateway, &brokenHits)
+	defer broken.Close()
+
+	var forwardErr error
+	srv := NewServer(&context.Context{Token: testToken})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply { return nil })
+	srv.SetForward(&ForwardConfig{
+		Rules: []*ForwardRule{
+			{Event: message.EventClick, EventKeyPrefix: "PROMO_", Upstreams: []*Upstream{{Name: "promo", URL: promo.URL + "/?app=1", Token: "promo-token"}}},
+			{MsgType: message.MsgTypeImage, Upstreams: []*Upstream{{Name: "broken", URL: broken.URL}}},
+		},
+		Default:      []*Upstream{{Name: "default", URL: fallback.URL}},
+		Retry:        RetryPolicy{MaxRetries: 1},
+		FallbackText: "busy",
+		OnError: func(msg message.MixMessage, err error) {
+			forwardErr = err
+		},
+	})
+
+	post := func(body string) string {
+		return postXML(srv, body)
+	}
+	common := "<ToUserName>gh_test</ToUserName><FromUserName>openid</FromUserName><CreateTime>1600000000</CreateTime>"
+
+	if reply := post("<xml>" + common + "<MsgType>event</MsgType><Event>CLICK</Event><EventKey>PROMO_1</EventKey></xml>"); !strings.Contains(reply, "promo") {
+		t.Errorf("expect reply of promo upstream, got %q", reply)
+	}
+	if reply := post("<xml>" + common + "<MsgType>text</MsgType><Content>hi</Content></xml>"); reply != "success" || defaultHits != 1 {
+		t.Errorf("expect forwarded to default upstream, got %q hits=%d", reply, defaultHits)
+	}
+	reply := post("<xml>" + common + "<MsgType>image</MsgType><PicUrl>pic</PicUrl></xml>")
+	var ferr *ForwardError
+	if !errors.As(forwardErr, &ferr) || ferr.Upstream != "broken" || ferr.Attempts != 2 || ferr.StatusCode != http.StatusBadGateway || brokenHits != 2 {
+		t.Errorf("expect ForwardError after retry, got %v hits=%d", forwardErr, brokenHits)
+	}
+	if !strings.Contains(reply, "busy") {
+		t.Errorf("expect fallback reply, got %q", reply)
+	}
+	if promoHits != 1 {
+		t.Errorf("expect promo upstream hit once, got %d", promoHits)
+	}
+}
+
+func TestServer_ForwardDeadline(t *testing.T) {
+	var hits int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer slow.Close()
+
+	var forwardErr error
+	srv := NewServer(&context.Context{Token: testToken})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply { return nil })
+	srv.SetForward(&ForwardConfig{
+		Default:  []*Upstream{{Name: "slow", URL: slow.URL}},
+		Retry:    RetryPolicy{MaxRetries: 100},
+		Deadline: 250 * time.Millisecond,
+		OnError: func(msg message.MixMessage, err error) {
+			forwardErr = err
+		},
+	})
+
+	start := time.Now()
+	postXML(srv, "<xml><ToUserName>gh_test</ToUserName><FromUserName>openid</FromUserName><CreateTime>1600000000</CreateTime><MsgType>text</MsgType><Content>hi</Content></xml>")
+	//重试次数很多时也在总时限内结束
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expect forward bounded by deadline, took %v", elapsed)
+	}
+	var ferr *ForwardError
+	if !errors.As(forwardErr, &ferr) || !ferr.Timeout() || ferr.Attempts >= 100 {
+		t.Errorf("expect deadline ForwardError, got %v hits=%d", forwardErr, atomic.LoadInt32(&hits))
+	}
+}
+
+func TestServer_MessageForwardSend(t *testing.T) {
+	var hits int32
+	upstream := newUpstream(t, "upstream-token", "success", http.StatusOK, &hits)
+	defer upstream.Close()
+
+	srv := NewServer(&context.Context{Token: testToken})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		return nil
+	})
+	w := httptest.NewRecorder()
+	s := srv.forRequest(httptest.NewRequest(http.MethodPost, "/?"+signQuery(""), strings.NewReader("<xml><ToUserName>gh_test</ToUserName><FromUserName>openid</FromUserName><CreateTime>1600000000</CreateTime><MsgType>text</MsgType><Content>hi</Content></xml>")), w)
+	if err := s.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	//postUrl中已有的签名原样保留
+	postURL := upstream.URL + "/?timestamp=1&nonce=n&signature=" + util.Signature("upstream-token", "1", "n")
+	s.MessageForwardSend(postURL, nil)
+	if w.Body.String() != "success" || atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("expect forwarded reply, got %q hits=%d", w.Body.String(), hits)
+	}
+	if s.forward != nil {
+		t.Error("expect forward config restored")
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/dcsunny/wechat/define"
+	"github.com/dcsunny/wechat/message"
+)
+
+//Middleware 包装消息处理方法，可在其前后执行逻辑或直接返回回复
+type Middleware func(next Handler) Handler
+
+//Use 添加中间件，按添加顺序由外到内执行，对之后处理的请求生效
+func (srv *Server) Use(middlewares ...Middleware) {
+	srv.middlewares = append(srv.middlewares, middlewares...)
+}
+
+//Chain 使用middlewares包装handler，第一个中间件在最外层；handler为nil时不回复
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	if handler == nil {
+		handler = func(msg message.MixMessage) *message.Reply {
+			return nil
+		}
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+//Logging 记录收到的原始消息、回复的消息类型及处理耗时
+func Logging(logf func(format string, args ...interface{})) Middleware {
+	return func(next Handler) Handler {
+		return func(msg message.MixMessage) *message.Reply {
+			start := time.Now()
+			reply := next(msg)
+			var replyType message.MsgType
+			if reply != nil {
+				replyType = reply.MsgType
+			}
+			logf("wechat message from=%s msgtype=%s event=%s reply=%s cost=%s raw=%s",
+				msg.FromUserName, msg.MsgType, msg.Event, replyType, time.Since(start), msg.Raw)
+			return reply
+		}
+	}
+}
+
+//Metrics 在每条消息处理完成后调用observe，用于统计消息数量及处理耗时
+func Metrics(observe func(msg message.MixMessage, reply *message.Reply, cost time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return func(msg message.MixMessage) *message.Reply {
+			start := time.Now()
+			reply := next(msg)
+			observe(msg, reply, time.Since(start))
+			return reply
+		}
+	}
+}
+
+//Recovery 处理方法panic时调用onPanic(可为nil)，并返回fallback的回复(fallback为nil时不回复)
+func Recovery(fallback Handler, onPanic func(msg message.MixMessage, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(msg message.MixMessage) (reply *message.Reply) {
+			defer func() {
+				if e := recover(); e != nil {
+					if onPanic != nil {
+						onPanic(msg, fmt.Errorf("panic error: %v\n%s", e, debug.Stack()))
+					}
+					reply = nil
+					if fallback != nil {
+						reply = fallback(msg)
+					}
+				}
+			}()
+			return next(msg)
+		}
+	}
+}
+
+//RateLimit 限制每个用户(FromUserName)在window时间内最多处理limit条消息，超出时返回limited的回复(limited为nil时不回复)。
+//计数保存在当前进程中
+func RateLimit(limit int, window time.Duration, limited Handler) Middleware {
+	limiter := &rateLimiter{limit: limit, window: window, counters: make(map[string]*rateCounter)}
+	return func(next Handler) Handler {
+		return func(msg message.MixMessage) *message.Reply {
+			if limiter.allow(string(msg.FromUserName)) {
+				return next(msg)
+			}
+			if limited != nil {
+				return limited(msg)
+			}
+			return nil
+		}
+	}
+}
+
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu       sync.Mutex
+	counters map[string]*rateCounter
+	cleaned  time.Time
+}
+
+type rateCounter struct {
+	start time.Time
+	count int
+}
+
+func (l *rateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	//定期清理过期的计数
+	if now.Sub(l.cleaned) > l.window {
+		for k, c := range l.counters {
+			if now.Sub(c.start) > l.window {
+				delete(l.counters, k)
+			}
+		}
+		l.cleaned = now
+	}
+
+	c, ok := l.counters[key]
+	if !ok || now.Sub(c.start) > l.window {
+		c = &rateCounter{start: now}
+		l.counters[key] = c
+	}
+	c.count++
+	return c.count <= l.limit
+}
+
+//ContentSecurity 对文本消息调用check检查内容(如小程序的MsgSecCheck)，
+//返回define.ErrRiskyContent类别的错误时返回risky的回复，其他错误忽略并继续处理
+func ContentSecurity(check func(content string) error, risky Handler) Middleware {
+	return func(next Handler) Handler {
+		return func(msg message.MixMessage) *message.Reply {
+			if msg.MsgType != message.MsgTypeText || msg.Content == "" {
+				return next(msg)
+			}
+			if err := check(msg.Content); errors.Is(err, define.ErrRiskyContent) {
+				if risky != nil {
+					return risky(msg)
+				}
+				return nil
+			}
+			return next(msg)
+		}
+	}
+}
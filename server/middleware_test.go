@@ -0,0 +1,93 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat/define"
+	"github.com/dcsunny/wechat/message"
+)
+
+func textReply(content string) Handler {
+	return func(msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(content)}
+	}
+}
+
+func replyContent(reply *message.Reply) string {
+	if reply == nil {
+		return ""
+	}
+	return string(reply.MsgData.(*message.Text).Content)
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(msg message.MixMessage) *message.Reply {
+				order = append(order, name)
+				return next(msg)
+			}
+		}
+	}
+	handler := Chain(textReply("ok"), trace("first"), trace("second"))
+	if got := replyContent(handler(message.MixMessage{})); got != "ok" {
+		t.Errorf("expect ok, got %s", got)
+	}
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("expect first,second, got %v", order)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	var recovered error
+	handler := Chain(func(msg message.MixMessage) *message.Reply {
+		panic("boom")
+	}, Recovery(textReply("系统繁忙"), func(msg message.MixMessage, err error) {
+		recovered = err
+	}))
+	if got := replyContent(handler(message.MixMessage{})); got != "系统繁忙" {
+		t.Errorf("expect fallback reply, got %s", got)
+	}
+	if recovered == nil || !strings.Contains(recovered.Error(), "boom") {
+		t.Errorf("expect recovered panic, got %v", recovered)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := Chain(textReply("ok"), RateLimit(2, time.Minute, textReply("limited")))
+	msg := message.MixMessage{}
+	msg.FromUserName = "openid"
+	other := message.MixMessage{}
+	other.FromUserName = "other"
+
+	for i, want := range []string{"ok", "ok", "limited"} {
+		if got := replyContent(handler(msg)); got != want {
+			t.Errorf("request %d: expect %s, got %s", i, want, got)
+		}
+	}
+	if got := replyContent(handler(other)); got != "ok" {
+		t.Errorf("expect other user not limited, got %s", got)
+	}
+}
+
+func TestContentSecurity(t *testing.T) {
+	check := func(content string) error {
+		if content == "bad" {
+			return define.NewError("MsgSecCheck", 87014, "risky content")
+		}
+		return nil
+	}
+	handler := Chain(textReply("ok"), ContentSecurity(check, textReply("risky")))
+	msg := message.MixMessage{Content: "bad"}
+	msg.MsgType = message.MsgTypeText
+	if got := replyContent(handler(msg)); got != "risky" {
+		t.Errorf("expect risky reply, got %s", got)
+	}
+	msg.Content = "good"
+	if got := replyContent(handler(msg)); got != "ok" {
+		t.Errorf("expect ok, got %s", got)
+	}
+}
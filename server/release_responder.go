@@ -0,0 +1,111 @@
+package server
+
+import (
+	stdcontext "context"
+	"strings"
+	"time"
+
+	"github.com/dcsunny/wechat/message"
+)
+
+const (
+	//ReleaseTestAppID 全网发布检测使用的公众号appid
+	ReleaseTestAppID = "wx570bc396a51b8ff8"
+	//ReleaseTestUserName 全网发布检测使用的公众号原始ID
+	ReleaseTestUserName = "gh_3c884a361561"
+
+	releaseTestText          = "TESTCOMPONENT_MSG_TYPE_TEXT"
+	releaseTestQueryAuthCode = "QUERY_AUTH_CODE:"
+	//releaseTestTimeout 检测要求在5秒内收到客服消息
+	releaseTestTimeout = 5 * time.Second
+)
+
+//EnableReleaseTest 开启全网发布检测的自动回复，检测账号的消息不再交给设置的处理方法：
+//事件回复"事件名from_callback"，文本TESTCOMPONENT_MSG_TYPE_TEXT回复TESTCOMPONENT_MSG_TYPE_TEXT_callback，
+//QUERY_AUTH_CODE:$query_auth_code$先回复空串，再在后台goroutine中使用授权码换取授权方token并通过客服消息发送"$query_auth_code$_from_api"，
+//换取或发送失败时调用onError。
+//
+//后台goroutine的超时为5秒（检测要求的客服消息时限），并在Shutdown时被取消；关闭服务前调用Shutdown等待其结束，
+//Shutdown后再次调用EnableReleaseTest可重新开启
+func (cs *ComponentServer) EnableReleaseTest(onError func(msg message.MixMessage, err error)) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.releaseTest = true
+	cs.releaseTestOnError = onError
+	cs.shutdown = false
+	if cs.releaseTestCancel == nil {
+		cs.releaseTestCtx, cs.releaseTestCancel = stdcontext.WithCancel(cs.StdContext())
+	}
+	//检测账号的Server已创建时重新创建
+	delete(cs.servers, ReleaseTestAppID)
+	cs.removeCached(ReleaseTestAppID)
+}
+
+//Shutdown 停止接收新的全网发布检测任务，并等待进行中的QUERY_AUTH_CODE回复结束；
+//ctx结束时取消仍在进行的任务并返回ctx.Err()
+func (cs *ComponentServer) Shutdown(ctx stdcontext.Context) error {
+	cs.mu.Lock()
+	cs.shutdown = true
+	cancel := cs.releaseTestCancel
+	//进行中的任务使用各自派生的context，清除后再次EnableReleaseTest时重新创建
+	cs.releaseTestCtx, cs.releaseTestCancel = nil, nil
+	cs.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		cs.releaseTestWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		cancel()
+		return nil
+	case <-ctx.Done():
+		cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+//releaseTestHandler 按全网发布检测的要求回复
+func (cs *ComponentServer) releaseTestHandler(msg message.MixMessage) *message.Reply {
+	switch {
+	case msg.MsgType == message.MsgTypeEvent:
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(string(msg.Event) + "from_callback")}
+	case msg.Content == releaseTestText:
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(releaseTestText + "_callback")}
+	case strings.HasPrefix(msg.Content, releaseTestQueryAuthCode):
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+		if cs.shutdown {
+			return nil
+		}
+		ctx, cancel := stdcontext.WithTimeout(cs.releaseTestCtx, releaseTestTimeout)
+		cs.releaseTestWG.Add(1)
+		go func() {
+			defer cs.releaseTestWG.Done()
+			defer cancel()
+			cs.replyQueryAuthCode(ctx, msg, strings.TrimPrefix(msg.Content, releaseTestQueryAuthCode))
+		}()
+	}
+	return nil
+}
+
+//replyQueryAuthCode 使用授权码换取授权方token，并以授权方身份发送客服消息
+func (cs *ComponentServer) replyQueryAuthCode(ctx stdcontext.Context, msg message.MixMessage, authCode string) {
+	err := func() error {
+		componentCtx := cs.WithContext(ctx)
+		info, err := componentCtx.QueryAuthCode(authCode)
+		if err != nil {
+			return err
+		}
+		manager := message.NewMessageManager(componentCtx.AuthorizerContext(info.Appid))
+		return manager.Send(message.NewCustomerTextMessage(string(msg.FromUserName), authCode+"_from_api"))
+	}()
+	if err != nil && cs.releaseTestOnError != nil {
+		cs.releaseTestOnError(msg, err)
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+)
+
+var xmlContentType = []string{"text/xml; charset=utf-8"}
+var jsonContentType = []string{"application/json; charset=utf-8"}
+var plainContentType = []string{"text/plain; charset=utf-8"}
+
+//Query returns the keyed url query value if it exists
+func (srv *Server) Query(key string) string {
+	value, _ := srv.GetQuery(key)
+	return value
+}
+
+//GetQuery is like Query(), it returns the keyed url query value
+func (srv *Server) GetQuery(key string) (string, bool) {
+	if values, ok := srv.Request.URL.Query()[key]; ok && len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+//Render render from bytes
+func (srv *Server) Render(bytes []byte) {
+	srv.Writer.WriteHeader(200)
+	_, err := srv.Writer.Write(bytes)
+	if err != nil {
+		panic(err)
+	}
+}
+
+//String render from string
+func (srv *Server) String(str string) {
+	writeContextType(srv.Writer, plainContentType)
+	srv.Render([]byte(str))
+}
+
+//XML render to xml
+func (srv *Server) XML(obj interface{}) {
+	writeContextType(srv.Writer, xmlContentType)
+	bytes, err := xml.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+	srv.Render(bytes)
+}
+
+//JSON render to json
+func (srv *Server) JSON(obj interface{}) {
+	writeContextType(srv.Writer, jsonContentType)
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+	srv.Render(bytes)
+}
+
+func writeContextType(w http.ResponseWriter, value []string) {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = value
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/dcsunny/wechat/message"
+)
+
+//Handler 处理一条消息，返回nil时不回复
+type Handler func(msg message.MixMessage) *message.Reply
+
+//Router 按消息类型、事件、EventKey分发消息，匹配的优先级从高到低为：
+//
+//	1. HandleEventKey 注册的事件及EventKey完全匹配(事件为空时匹配任意事件，优先级低于指定事件)
+//	2. HandleEventKeyPrefix 注册的EventKey前缀，最长的前缀优先，长度相同时指定事件的优先
+//	3. HandleEvent 注册的事件
+//	4. HandleMsgType 注册的消息类型(事件推送的类型为message.MsgTypeEvent)
+//	5. Fallback 注册的默认处理方法
+//
+//都不匹配时不回复。Router应在开始处理请求前完成注册，之后可以并发使用
+type Router struct {
+	msgTypes  map[message.MsgType]Handler
+	events    map[message.EventType]Handler
+	eventKeys map[eventKey]Handler
+	prefixes  []prefixRoute
+	fallback  Handler
+}
+
+type eventKey struct {
+	event message.EventType
+	key   string
+}
+
+type prefixRoute struct {
+	event   message.EventType
+	prefix  string
+	handler Handler
+}
+
+//NewRouter 创建Router
+func NewRouter() *Router {
+	return &Router{
+		msgTypes:  make(map[message.MsgType]Handler),
+		events:    make(map[message.EventType]Handler),
+		eventKeys: make(map[eventKey]Handler),
+	}
+}
+
+//HandleMsgType 处理指定类型的消息，如message.MsgTypeText
+func (r *Router) HandleMsgType(msgType message.MsgType, handler Handler) {
+	r.msgTypes[msgType] = handler
+}
+
+//HandleEvent 处理指定的事件推送，如message.EventSubscribe、message.EventClick
+func (r *Router) HandleEvent(event message.EventType, handler Handler) {
+	r.events[event] = handler
+}
+
+//HandleEventKey 处理EventKey为key的事件推送，如菜单的key，event为空时匹配任意事件
+func (r *Router) HandleEventKey(event message.EventType, key string, handler Handler) {
+	r.eventKeys[eventKey{event, key}] = handler
+}
+
+//HandleEventKeyPrefix 处理EventKey以prefix开头的事件推送，如扫码关注的"qrscene_"，event为空时匹配任意事件
+func (r *Router) HandleEventKeyPrefix(event message.EventType, prefix string, handler Handler) {
+	for i, route := range r.prefixes {
+		if route.event == event && route.prefix == prefix {
+			r.prefixes[i].handler = handler
+			return
+		}
+	}
+	r.prefixes = append(r.prefixes, prefixRoute{event, prefix, handler})
+}
+
+//Fallback 处理其他未匹配的消息
+func (r *Router) Fallback(handler Handler) {
+	r.fallback = handler
+}
+
+//Match 返回处理msg的Handler，没有匹配时返回nil
+func (r *Router) Match(msg message.MixMessage) Handler {
+	if msg.MsgType == message.MsgTypeEvent {
+		if handler := r.matchEvent(msg); handler != nil {
+			return handler
+		}
+	}
+	if handler, ok := r.msgTypes[msg.MsgType]; ok {
+		return handler
+	}
+	return r.fallback
+}
+
+func (r *Router) matchEvent(msg message.MixMessage) Handler {
+	if handler, ok := r.eventKeys[eventKey{msg.Event, msg.EventKey}]; ok {
+		return handler
+	}
+	if handler, ok := r.eventKeys[eventKey{"", msg.EventKey}]; ok {
+		return handler
+	}
+
+	var matched *prefixRoute
+	for i, route := range r.prefixes {
+		if route.event != "" && route.event != msg.Event {
+			continue
+		}
+		if !strings.HasPrefix(msg.EventKey, route.prefix) {
+			continue
+		}
+		if matched == nil || len(route.prefix) > len(matched.prefix) ||
+			(len(route.prefix) == len(matched.prefix) && matched.event == "") {
+			matched = &r.prefixes[i]
+		}
+	}
+	if matched != nil {
+		return matched.handler
+	}
+
+	return r.events[msg.Event]
+}
+
+//Handle 分发msg，可作为SetMessageHandler的参数
+func (r *Router) Handle(msg message.MixMessage) *message.Reply {
+	if handler := r.Match(msg); handler != nil {
+		return handler(msg)
+	}
+	return nil
+}
+
+//Router 返回Server使用的Router，首次调用时创建并设置为消息处理方法
+func (srv *Server) Router() *Router {
+	if srv.router == nil {
+		srv.router = NewRouter()
+		srv.SetMessageHandler(srv.router.Handle)
+	}
+	return srv.router
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/dcsunny/wechat/message"
+)
+
+func TestRouter_Match(t *testing.T) {
+	named := func(name string) Handler {
+		return func(msg message.MixMessage) *message.Reply {
+			return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(name)}
+		}
+	}
+	r := NewRouter()
+	r.HandleMsgType(message.MsgTypeText, named("text"))
+	r.HandleMsgType(message.MsgTypeEvent, named("event"))
+	r.HandleEvent(message.EventSubscribe, named("subscribe"))
+	r.HandleEvent(message.EventClick, named("click"))
+	r.HandleEventKey(message.EventClick, "menu_help", named("click help"))
+	r.HandleEventKey("", "menu_help", named("any help"))
+	r.HandleEventKeyPrefix("", "qrscene_", named("qrscene"))
+	r.HandleEventKeyPrefix(message.EventSubscribe, "qrscene_", named("subscribe qrscene"))
+	r.HandleEventKeyPrefix(message.EventSubscribe, "qrscene_vip_", named("subscribe vip"))
+	r.Fallback(named("fallback"))
+
+	tests := []struct {
+		msgType  message.MsgType
+		event    message.EventType
+		eventKey string
+		want     string
+	}{
+		{message.MsgTypeText, "", "", "text"},
+		{message.MsgTypeImage, "", "", "fallback"},
+		{message.MsgTypeEvent, message.EventClick, "menu_help", "click help"},
+		{message.MsgTypeEvent, message.EventView, "menu_help", "any help"},
+		{message.MsgTypeEvent, message.EventClick, "menu_other", "click"},
+		{message.MsgTypeEvent, message.EventSubscribe, "qrscene_vip_1", "subscribe vip"},
+		{message.MsgTypeEvent, message.EventSubscribe, "qrscene_1", "subscribe qrscene"},
+		{message.MsgTypeEvent, message.EventScan, "qrscene_1", "qrscene"},
+		{message.MsgTypeEvent, message.EventSubscribe, "", "subscribe"},
+		{message.MsgTypeEvent, message.EventLocation, "", "event"},
+	}
+	for _, tt := range tests {
+		msg := message.MixMessage{Event: tt.event, EventKey: tt.eventKey}
+		msg.MsgType = tt.msgType
+		reply := r.Handle(msg)
+		if got := string(reply.MsgData.(*message.Text).Content); got != tt.want {
+			t.Errorf("%s %s %q: expect %s, got %s", tt.msgType, tt.event, tt.eventKey, tt.want, got)
+		}
+	}
+}
@@ -1,16 +1,12 @@
 package server
 
 import (
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"reflect"
 	"runtime/debug"
 	"strconv"
-
-	"strings"
-
 	"time"
 
 	"net/http"
@@ -21,12 +17,27 @@ import (
 )
 
 //Server struct
+//
+//通过NewServer创建的Server实现了http.Handler，可被多个请求并发使用，每个请求使用独立的副本处理；
+//Request、Writer以及解析得到的消息等都属于单个请求，不会写入共享的Context
 type Server struct {
 	*context.Context
 
+	Request *http.Request
+	Writer  http.ResponseWriter
+
 	openID string
 
 	messageHandler func(message.MixMessage) *message.Reply
+	router         *Router
+	middlewares    []Middleware
+	dedup          *DedupConfig
+	async          *AsyncConfig
+	dataType       DataType
+	validateConfig *ValidateConfig
+	forward        *ForwardConfig
+	component      *ComponentHooks
+	errorHandler   func(w http.ResponseWriter, r *http.Request, err error)
 
 	requestRawXMLMsg    []byte
 	requestMsg          message.MixMessage
@@ -34,13 +45,19 @@ type Server struct {
 	responseNeedForward bool
 	responseMsg         interface{}
 
+	dedupKey       string
+	duplicate      bool
+	asyncTimedOut  bool
+	componentEvent bool
+
 	isSafeMode bool
+	isJSON     bool
 	random     []byte
 	nonce      string
 	timestamp  int64
 
-	mssageForwardUrl    string
-	messageForwardToken string
+	//started 开始处理请求的时间，用于计算转发的总时限
+	started time.Time
 }
 
 //NewServer init
@@ -50,10 +67,49 @@ func NewServer(context *context.Context) *Server {
 	return srv
 }
 
+//NewRequestServer 创建处理单个请求的Server，之后调用Serve、Send处理消息接收以及回复
+func NewRequestServer(context *context.Context, req *http.Request, writer http.ResponseWriter) *Server {
+	srv := NewServer(context)
+	srv.Request = req
+	srv.Writer = writer
+	return srv
+}
+
+//ServeHTTP 处理微信的请求消息并回复，出错时调用SetErrorHandler设置的方法，默认返回400
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := srv.forRequest(r, w)
+	err := s.Serve()
+	if err == nil {
+		err = s.Send()
+	}
+	if err != nil {
+		if srv.errorHandler != nil {
+			srv.errorHandler(w, r, err)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+//SetErrorHandler 设置ServeHTTP处理请求出错时的回调
+func (srv *Server) SetErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) {
+	srv.errorHandler = handler
+}
+
+//forRequest 返回处理单个请求的副本，ServeHTTP不会修改srv自身的请求状态，复制得到的只有配置
+func (srv *Server) forRequest(r *http.Request, w http.ResponseWriter) *Server {
+	s := new(Server)
+	*s = *srv
+	s.Request = r
+	s.Writer = w
+	return s
+}
+
 //Serve 处理微信的请求消息
 func (srv *Server) Serve() error {
-	if !srv.Validate() {
-		return fmt.Errorf("请求校验失败")
+	srv.started = time.Now()
+	if err := srv.ValidateRequest(); err != nil {
+		return err
 	}
 
 	echostr, exists := srv.GetQuery("echostr")
@@ -66,16 +122,16 @@ func (srv *Server) Serve() error {
 	if err != nil {
 		return err
 	}
+	if srv.duplicate {
+		return nil
+	}
 
-	return srv.buildResponse(response)
-}
-
-//Validate 校验请求是否合法
-func (srv *Server) Validate() bool {
-	timestamp := srv.Query("timestamp")
-	nonce := srv.Query("nonce")
-	signature := srv.Query("signature")
-	return signature == util.Signature(srv.Token, timestamp, nonce)
+	if err = srv.buildResponse(response); err != nil {
+		//处理失败时允许微信重试
+		srv.clearDedup()
+		return err
+	}
+	return srv.saveReply()
 }
 
 //HandleRequest 处理微信的请求
@@ -100,7 +156,35 @@ func (srv *Server) handleRequest() (reply *message.Reply, err error) {
 		err = errors.New("消息类型转换失败")
 	}
 	srv.requestMsg = mixMessage
-	reply = srv.messageHandler(mixMessage)
+	if srv.component != nil && mixMessage.InfoType != "" {
+		srv.componentEvent = true
+		err = srv.handleComponentEvent(mixMessage)
+		return
+	}
+	if srv.dedup != nil {
+		var cachedReply []byte
+		srv.duplicate, cachedReply, err = srv.checkDuplicate(mixMessage)
+		if err != nil {
+			return
+		}
+		if srv.duplicate {
+			if len(cachedReply) > 0 {
+				srv.responseRawXMLMsg = cachedReply
+				srv.responseMsg = rawReply(cachedReply)
+			}
+			return
+		}
+	}
+	if srv.dedupKey != "" {
+		defer func() {
+			if e := recover(); e != nil {
+				//处理方法panic时删除标记，避免微信的重试都被当作重复消息丢弃
+				srv.clearDedup()
+				panic(e)
+			}
+		}()
+	}
+	reply = srv.callHandler(mixMessage)
 	return
 }
 
@@ -111,12 +195,17 @@ func (srv *Server) GetOpenID() string {
 
 //getMessage 解析微信返回的消息
 func (srv *Server) getMessage() (interface{}, error) {
+	body, err := ioutil.ReadAll(srv.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取body失败, err=%v", err)
+	}
+	srv.isJSON = srv.detectJSON(body)
+
 	var rawXMLMsgBytes []byte
-	var err error
 	if srv.isSafeMode {
 		var encryptedXMLMsg message.EncryptedXMLMsg
-		if err := xml.NewDecoder(srv.Request.Body).Decode(&encryptedXMLMsg); err != nil {
-			return nil, fmt.Errorf("从body中解析xml失败,err=%v", err)
+		if err := srv.unmarshal(body, &encryptedXMLMsg); err != nil {
+			return nil, fmt.Errorf("从body中解析%s失败,err=%v", srv.format(), err)
 		}
 
 		//验证消息签名
@@ -129,20 +218,17 @@ func (srv *Server) getMessage() (interface{}, error) {
 		srv.nonce = nonce
 		msgSignature := srv.Query("msg_signature")
 		msgSignatureGen := util.Signature(srv.Token, timestamp, nonce, encryptedXMLMsg.EncryptedMsg)
-		if msgSignature != msgSignatureGen {
-			return nil, fmt.Errorf("消息不合法，验证签名失败")
+		if !equalSignature(msgSignature, msgSignatureGen) {
+			return nil, ErrInvalidMsgSignature
 		}
 
 		//解密
-		srv.random, rawXMLMsgBytes, err = util.DecryptMsg(srv.AppID, encryptedXMLMsg.EncryptedMsg, srv.EncodingAESKey)
+		srv.random, rawXMLMsgBytes, err = util.DecryptMsg(srv.cryptoAppID(), encryptedXMLMsg.EncryptedMsg, srv.EncodingAESKey)
 		if err != nil {
 			return nil, fmt.Errorf("消息解密失败, err=%v", err)
 		}
 	} else {
-		rawXMLMsgBytes, err = ioutil.ReadAll(srv.Request.Body)
-		if err != nil {
-			return nil, fmt.Errorf("从body中解析xml失败, err=%v", err)
-		}
+		rawXMLMsgBytes = body
 	}
 
 	srv.requestRawXMLMsg = rawXMLMsgBytes
@@ -152,10 +238,19 @@ func (srv *Server) getMessage() (interface{}, error) {
 
 func (srv *Server) parseRequestMessage(rawXMLMsgBytes []byte) (msg message.MixMessage, err error) {
 	msg = message.MixMessage{}
-	err = xml.Unmarshal(rawXMLMsgBytes, &msg)
+	err = srv.unmarshal(rawXMLMsgBytes, &msg)
+	msg.Raw = rawXMLMsgBytes
 	return
 }
 
+//cryptoAppID 安全模式下加解密消息使用的appid，代授权方接收消息时为第三方平台的appid
+func (srv *Server) cryptoAppID() string {
+	if appID := srv.ComponentAppID(); appID != "" {
+		return appID
+	}
+	return srv.AppID
+}
+
 //SetMessageHandler 设置用户自定义的回调方法
 func (srv *Server) SetMessageHandler(handler func(message.MixMessage) *message.Reply) {
 	srv.messageHandler = handler
@@ -213,37 +308,54 @@ func (srv *Server) buildResponse(reply *message.Reply) (err error) {
 	value.MethodByName("SetCreateTime").Call(params)
 
 	srv.responseMsg = msgData
-	srv.responseRawXMLMsg, err = xml.Marshal(msgData)
+	srv.responseRawXMLMsg, err = srv.marshal(msgData)
 	return
 }
 
 //Send 将自定义的消息发送
 func (srv *Server) Send() (err error) {
+	if srv.asyncTimedOut || srv.componentEvent {
+		//异步回复将通过客服消息发送；第三方平台授权事件需回复success
+		srv.String("success")
+		return
+	}
 	replyMsg, err := srv.sendBuildMsg(srv.responseMsg)
 	if err != nil {
 		return
 	}
 	if replyMsg != nil {
-		srv.XML(replyMsg)
-	} else {
-		if srv.responseNeedForward {
-			if srv.mssageForwardUrl != "" {
-				srv.MessageForward()
-			}
-		}
+		srv.writeReply(replyMsg)
+	} else if srv.responseNeedForward && srv.forward != nil {
+		srv.MessageForward()
 	}
 	return
 }
-func (srv *Server) SetMessageForward(url string, token string) {
-	srv.mssageForwardUrl = url
-	srv.messageForwardToken = token
+
+//writeReply 按请求的格式输出回复
+func (srv *Server) writeReply(replyMsg interface{}) {
+	if raw, ok := replyMsg.(rawReply); ok {
+		if srv.isJSON {
+			writeContextType(srv.Writer, jsonContentType)
+		} else {
+			writeContextType(srv.Writer, xmlContentType)
+		}
+		srv.Render(raw)
+	} else if srv.isJSON {
+		srv.JSON(replyMsg)
+	} else {
+		srv.XML(replyMsg)
+	}
 }
+
 func (srv *Server) sendBuildMsg(replyMsg interface{}) (interface{}, error) {
+	if replyMsg == nil {
+		return nil, nil
+	}
 	if srv.isSafeMode {
 		//安全模式下对消息进行加密
 		var encryptedMsg []byte
 		var err error
-		encryptedMsg, err = util.EncryptMsg(srv.random, srv.responseRawXMLMsg, srv.AppID, srv.EncodingAESKey)
+		encryptedMsg, err = util.EncryptMsg(srv.random, srv.responseRawXMLMsg, srv.cryptoAppID(), srv.EncodingAESKey)
 		if err != nil {
 			return nil, err
 		}
@@ -260,45 +372,3 @@ func (srv *Server) sendBuildMsg(replyMsg interface{}) (interface{}, error) {
 	}
 	return replyMsg, nil
 }
-func (srv *Server) MessageForward() {
-	signature := util.Signature(srv.messageForwardToken, fmt.Sprint(srv.timestamp), srv.nonce)
-	postUrl := srv.mssageForwardUrl + fmt.Sprintf("&timestamp=%d&nonce=%s&signature=%s", srv.timestamp, srv.nonce, signature)
-	retryNum := 0
-	srv.MessageForwardSend(postUrl, &retryNum)
-}
-
-func (srv *Server) MessageForwardSend(postUrl string, retryNum *int) {
-
-	timeout := 4500 * time.Millisecond
-	client := &http.Client{
-		Timeout: timeout,
-	}
-	resp, err := util.PostXML(postUrl, srv.requestMsg, client)
-	if err != nil {
-		if strings.Contains(err.Error(), "request canceled (Client.Timeout exceeded while awaiting headers)") {
-			msg := &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("系统异常,请稍后再试")}
-			err = srv.buildResponse(msg)
-			if err != nil {
-				fmt.Println("error:", err.Error())
-				return
-			}
-			srv.XML(srv.responseMsg)
-			return
-		} else if strings.Contains(err.Error(), "request canceled while waiting for connection (Client.Timeout exceeded while awaiting headers)") {
-			_retryNum := 0
-			if retryNum != nil {
-				_retryNum = *retryNum
-			}
-			if _retryNum > 1 {
-				return
-			}
-			_retryNum++
-			retryNum = &_retryNum
-			srv.MessageForwardSend(postUrl, retryNum)
-			return
-		}
-		fmt.Println("http error,err:", err.Error())
-		return
-	}
-	srv.Render(resp)
-}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/message"
+	"github.com/dcsunny/wechat/util"
+)
+
+//testToken 测试中Server使用的Token
+const testToken = "token"
+
+//signQuery 按微信的方式使用当前时间和随机nonce计算签名参数，encrypted不为空时按安全模式同时计算msg_signature
+func signQuery(encrypted string) string {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := util.RandomStr(10)
+	query := url.Values{}
+	query.Set("timestamp", timestamp)
+	query.Set("nonce", nonce)
+	query.Set("signature", util.Signature(testToken, timestamp, nonce))
+	if encrypted != "" {
+		query.Set("encrypt_type", "aes")
+		query.Set("msg_signature", util.Signature(testToken, timestamp, nonce, encrypted))
+	}
+	return query.Encode()
+}
+
+//postSigned 将body以签名的请求推送给handler，query为signQuery返回的参数
+func postSigned(handler http.Handler, query, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/?"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+//postXML 将XML消息以签名的请求推送给handler，返回回复的内容
+func postXML(handler http.Handler, body string) string {
+	return postSigned(handler, signQuery(""), "text/xml", body).Body.String()
+}
+
+func TestServer_ServeHTTPConcurrent(t *testing.T) {
+	srv := NewServer(&context.Context{Token: testToken})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(msg.Content)}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := fmt.Sprintf("user%d", i)
+			body := fmt.Sprintf("<xml><ToUserName>gh_test</ToUserName><FromUserName>%s</FromUserName><CreateTime>1600000000</CreateTime><MsgType>text</MsgType><Content>%s</Content></xml>", user, user)
+			data := postXML(srv, body)
+			var reply message.Text
+			if err := xml.Unmarshal([]byte(data), &reply); err != nil {
+				t.Errorf("unmarshal reply %s: %v", data, err)
+				return
+			}
+			if string(reply.ToUserName) != user || string(reply.Content) != user {
+				t.Errorf("expect reply to %s, got %s", user, data)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if w := postSigned(srv, "timestamp=1&nonce=nonce&signature=bad", "text/xml", "<xml></xml>"); w.Code != http.StatusBadRequest {
+		t.Errorf("expect 400 for invalid signature, got %d", w.Code)
+	}
+}
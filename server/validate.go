@@ -0,0 +1,128 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/util"
+)
+
+const (
+	defaultMaxSkew = 5 * time.Minute
+	nonceCacheKey  = "wechat_callback_nonce:%s:%s:%s"
+)
+
+var (
+	//ErrMissingSignature 请求中缺少signature、timestamp或nonce
+	ErrMissingSignature = errors.New("请求校验失败，缺少签名参数")
+	//ErrInvalidSignature signature不正确
+	ErrInvalidSignature = errors.New("请求校验失败，签名不正确")
+	//ErrInvalidTimestamp timestamp格式不正确
+	ErrInvalidTimestamp = errors.New("请求校验失败，timestamp不合法")
+	//ErrTimestampExpired timestamp与当前时间的偏差超过MaxSkew
+	ErrTimestampExpired = errors.New("请求校验失败，timestamp已过期")
+	//ErrNonceReused nonce在有效期内被重复使用，可能是重放的请求
+	ErrNonceReused = errors.New("请求校验失败，nonce重复")
+	//ErrInvalidMsgSignature 安全模式下msg_signature不正确
+	ErrInvalidMsgSignature = errors.New("消息不合法，验证签名失败")
+)
+
+//ValidateConfig 请求校验配置，用于防止请求被重放
+type ValidateConfig struct {
+	//MaxSkew timestamp与当前时间允许的最大偏差，默认5分钟
+	MaxSkew time.Duration
+	//CheckNonce 为true时拒绝MaxSkew的两倍时间内重复使用的timestamp+nonce。
+	//注意微信重试推送时可能使用相同的nonce，需要处理重试时可配合SetDedup使用
+	CheckNonce bool
+	//Cache 记录已使用的nonce，为空时使用Context.Cache；实现了cache.Locker时使用其原子地标记nonce
+	Cache cache.Cache
+}
+
+//SetValidateConfig 设置请求校验，cfg为nil时只校验签名
+func (srv *Server) SetValidateConfig(cfg *ValidateConfig) {
+	if cfg == nil {
+		srv.validateConfig = nil
+		return
+	}
+	validateConfig := *cfg
+	if validateConfig.MaxSkew <= 0 {
+		validateConfig.MaxSkew = defaultMaxSkew
+	}
+	srv.validateConfig = &validateConfig
+}
+
+//Validate 校验请求是否合法，只校验签名及timestamp，不记录nonce
+func (srv *Server) Validate() bool {
+	return srv.checkSignature() == nil
+}
+
+//ValidateRequest 校验请求是否合法，不合法时返回ErrMissingSignature、ErrInvalidSignature等错误；
+//开启CheckNonce时会记录本次请求的nonce，同一请求只能校验一次
+func (srv *Server) ValidateRequest() error {
+	if err := srv.checkSignature(); err != nil {
+		return err
+	}
+	if srv.validateConfig == nil || !srv.validateConfig.CheckNonce {
+		return nil
+	}
+	return srv.checkNonce()
+}
+
+func (srv *Server) checkSignature() error {
+	timestamp := srv.Query("timestamp")
+	nonce := srv.Query("nonce")
+	signature := srv.Query("signature")
+	if signature == "" || timestamp == "" || nonce == "" {
+		return ErrMissingSignature
+	}
+	if !equalSignature(signature, util.Signature(srv.Token, timestamp, nonce)) {
+		return ErrInvalidSignature
+	}
+	if srv.validateConfig == nil {
+		return nil
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	skew := time.Since(time.Unix(ts, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > srv.validateConfig.MaxSkew {
+		return ErrTimestampExpired
+	}
+	return nil
+}
+
+func (srv *Server) checkNonce() error {
+	c := srv.validateConfig.Cache
+	if c == nil {
+		c = srv.Cache
+	}
+	key := fmt.Sprintf(nonceCacheKey, srv.AppID, srv.Query("timestamp"), srv.Query("nonce"))
+	expiration := 2 * srv.validateConfig.MaxSkew
+	if locker, ok := c.(cache.Locker); ok {
+		acquired, err := locker.Acquire(key, "1", expiration)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			return ErrNonceReused
+		}
+		return nil
+	}
+	if c.IsExist(key) {
+		return ErrNonceReused
+	}
+	return c.SetString(key, "1", expiration)
+}
+
+//equalSignature 使用固定时间比较签名，避免通过响应时间推测签名
+func equalSignature(signature, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(signature), []byte(expected)) == 1
+}
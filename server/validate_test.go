@@ -0,0 +1,50 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/context"
+	"github.com/dcsunny/wechat/util"
+)
+
+func TestServer_ValidateRequest(t *testing.T) {
+	srv := NewServer(&context.Context{Token: testToken, Cache: cache.NewMemory()})
+	var lastErr error
+	srv.SetErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		lastErr = err
+	})
+	srv.SetValidateConfig(&ValidateConfig{MaxSkew: time.Minute, CheckNonce: true})
+
+	verify := func(timestamp, nonce, signature string) error {
+		lastErr = nil
+		uri := "/?echostr=echo&timestamp=" + timestamp + "&nonce=" + nonce + "&signature=" + signature
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, uri, nil))
+		return lastErr
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	expired := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name                        string
+		timestamp, nonce, signature string
+		want                        error
+	}{
+		{"valid", now, "nonce1", util.Signature(testToken, now, "nonce1"), nil},
+		{"replayed", now, "nonce1", util.Signature(testToken, now, "nonce1"), ErrNonceReused},
+		{"missing", now, "nonce2", "", ErrMissingSignature},
+		{"invalid signature", now, "nonce3", util.Signature("other", now, "nonce3"), ErrInvalidSignature},
+		{"expired", expired, "nonce4", util.Signature(testToken, expired, "nonce4"), ErrTimestampExpired},
+		{"invalid timestamp", "abc", "nonce5", util.Signature(testToken, "abc", "nonce5"), ErrInvalidTimestamp},
+	}
+	for _, tt := range tests {
+		if err := verify(tt.timestamp, tt.nonce, tt.signature); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expect %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package shopping_guide
 
-import "github.com/dcsunny/wechat/context"
+import (
+	stdcontext "context"
+
+	"github.com/dcsunny/wechat/context"
+)
 
 type Guide struct {
 	*context.Context
@@ -13,6 +17,7 @@ type Guide struct {
 
 func NewGuide(ctx *context.Context) *Guide {
 	guide := new(Guide)
+	guide.Context = ctx
 	guide.GuideManager = NewGuideManager(ctx)
 	guide.GuideBuyer = NewGuideBuyer(ctx)
 	guide.GuideTag = NewGuideTag(ctx)
@@ -20,3 +25,8 @@ func NewGuide(ctx *context.Context) *Guide {
 	guide.GuideMaterial = NewGuideMaterial(ctx)
 	return guide
 }
+
+//WithContext 返回绑定了ctx的Guide，其发起的请求受ctx的取消和超时控制
+func (guide *Guide) WithContext(ctx stdcontext.Context) *Guide {
+	return NewGuide(guide.Context.WithContext(ctx))
+}
@@ -1,6 +1,7 @@
 package shopping_guide
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
@@ -8,7 +9,6 @@ import (
 
 	"github.com/dcsunny/wechat/common_error"
 	"github.com/dcsunny/wechat/context"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -29,6 +29,11 @@ func NewGuideBuyer(ctx *context.Context) *GuideBuyer {
 	return &GuideBuyer{ctx}
 }
 
+//WithContext 返回绑定了ctx的GuideBuyer，其发起的请求受ctx的取消和超时控制
+func (g *GuideBuyer) WithContext(ctx stdcontext.Context) *GuideBuyer {
+	return NewGuideBuyer(g.Context.WithContext(ctx))
+}
+
 type BuyerInfo struct {
 	OpenID        string `json:"openid"`
 	BuyerNickname string `json:"buyer_nickname"`
@@ -50,7 +55,7 @@ func (g *GuideBuyer) Add(req GuideBuyerRelationAddReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideBuyerRelationAdd, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -72,7 +77,7 @@ func (g *GuideBuyer) Delete(req GuideBuyerRelationDeleteReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideBuyerRelationDelete, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -99,7 +104,7 @@ func (g *GuideBuyer) List(req GuideBuyerRelationListReq) (*GuideBuyerRelationLis
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideBuyerRelationList, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -130,7 +135,7 @@ func (g *GuideBuyer) Rebind(req GuideBuyerRelationRebindReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideBuyerRelationRebind, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -151,7 +156,7 @@ func (g *GuideBuyer) UpdateNickname(req BuyerUpdateNicknameReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideBuyerUpdateNickname, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -174,7 +179,7 @@ func (g *GuideBuyer) GetGuideByBuyer(openID string) (GetGuideByBuyerResp, error)
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GetGuideBuyerRelationByBuyer, accessToken)
-	response, err := util.PostJSON(uri, map[string]interface{}{
+	response, err := g.PostJSON(uri, map[string]interface{}{
 		"openid": openID,
 	})
 	if err != nil {
@@ -209,7 +214,7 @@ func (g *GuideBuyer) GetGuideBuyerRelation(req GetGuideBuyerRelationReq) (BuyerI
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GetGuideBuyerRelation, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return BuyerInfo{}, err
 	}
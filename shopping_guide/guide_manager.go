@@ -1,13 +1,13 @@
 package shopping_guide
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/dcsunny/wechat/define"
 
 	"github.com/dcsunny/wechat/common_error"
-	"github.com/dcsunny/wechat/util"
 
 	"github.com/dcsunny/wechat/context"
 )
@@ -29,6 +29,11 @@ func NewGuideManager(ctx *context.Context) *GuideManager {
 	return &GuideManager{ctx}
 }
 
+//WithContext 返回绑定了ctx的GuideManager，其发起的请求受ctx的取消和超时控制
+func (g *GuideManager) WithContext(ctx stdcontext.Context) *GuideManager {
+	return NewGuideManager(g.Context.WithContext(ctx))
+}
+
 type GuideManagerAddReq struct {
 	Account    string `json:"guide_account"`    //顾问微信号（guide_account和guide_openid二选一）
 	OpenID     string `json:"guide_openid"`     //顾问openid或者unionid（guide_account和guide_openid二选一）
@@ -58,7 +63,7 @@ func (g *GuideManager) AddGuideAcct(req GuideManagerAddReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideManagerAcctAdd, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -73,7 +78,7 @@ func (g *GuideManager) GetGuideAcct(req GuideManagerAcctReq) (*GuideAcctInfo, er
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideManagerAcctGet, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +101,7 @@ func (g *GuideManager) UpdateGuideAcct(req GuideManagerAddReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideManagerAcctUpdate, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -111,7 +116,7 @@ func (g *GuideManager) DeleteGuideAcct(req GuideManagerAcctReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideManagerAcctDelete, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -132,7 +137,7 @@ func (g *GuideManager) GetGuideAcctList(page int, num int) (GuideAcctList, error
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideManagerAcctList, accessToken)
-	response, err := util.PostJSON(uri, map[string]interface{}{
+	response, err := g.PostJSON(uri, map[string]interface{}{
 		"page": page,
 		"num":  num,
 	})
@@ -168,7 +173,7 @@ func (g *GuideManager) CreateQrCode(req GuideCreateQrCodeReq) (string, error) {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", GuideManagerAcctCreateQrCode, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return "", err
 	}
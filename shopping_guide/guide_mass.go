@@ -1,13 +1,13 @@
 package shopping_guide
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/dcsunny/wechat/common_error"
 	"github.com/dcsunny/wechat/context"
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -41,6 +41,11 @@ func NewGuideMass(ctx *context.Context) *GuideMass {
 	return &GuideMass{ctx}
 }
 
+//WithContext 返回绑定了ctx的GuideMass，其发起的请求受ctx的取消和超时控制
+func (g *GuideMass) WithContext(ctx stdcontext.Context) *GuideMass {
+	return NewGuideMass(g.Context.WithContext(ctx))
+}
+
 type AddGuideMasssendJobReq struct {
 	GuideAccount string                `json:"guide_account"`
 	GuideOpenID  string                `json:"guide_openid"`
@@ -77,7 +82,7 @@ func (g *GuideMass) AddGuideMasssendJob(req AddGuideMasssendJobReq) ([]AddGuideM
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", addGuideMasssendJob, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -131,7 +136,7 @@ func (g *GuideMass) GetGuideMassendJobList(req GetGuideMassendJobListReq) (*GetG
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", getGuideMassendJobList, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +163,7 @@ func (g *GuideMass) GetGuideMassendJob(taskID int64) (GuideMassJob, error) {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", getGuideMassendJob, accessToken)
-	response, err := util.PostJSON(uri, map[string]interface{}{
+	response, err := g.PostJSON(uri, map[string]interface{}{
 		"task_id": taskID,
 	})
 	if err != nil {
@@ -192,7 +197,7 @@ func (g *GuideMass) UpdateGuideMasssendJob(req UpdateGuideMasssendJobReq) error
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", updateGuideMasssendJob, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -206,7 +211,7 @@ func (g *GuideMass) CancelGuideMassendJob(taskID int64) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", cancelGuideMassendJob, accessToken)
-	response, err := util.PostJSON(uri, map[string]interface{}{
+	response, err := g.PostJSON(uri, map[string]interface{}{
 		"task_id": taskID,
 	})
 	if err != nil {
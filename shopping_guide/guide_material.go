@@ -1,6 +1,7 @@
 package shopping_guide
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
@@ -8,7 +9,6 @@ import (
 
 	"github.com/dcsunny/wechat/common_error"
 	"github.com/dcsunny/wechat/context"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -31,6 +31,11 @@ func NewGuideMaterial(ctx *context.Context) *GuideMaterial {
 	return &GuideMaterial{ctx}
 }
 
+//WithContext 返回绑定了ctx的GuideMaterial，其发起的请求受ctx的取消和超时控制
+func (g *GuideMaterial) WithContext(ctx stdcontext.Context) *GuideMaterial {
+	return NewGuideMaterial(g.Context.WithContext(ctx))
+}
+
 type SetGuideCardMaterialReq struct {
 	AppID   string `json:"appid"`
 	MediaID string `json:"media_id"`
@@ -46,7 +51,7 @@ func (g *GuideMaterial) SetGuideCardMaterial(req SetGuideCardMaterialReq) error
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", setGuideCardMaterial, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -74,7 +79,7 @@ func (g *GuideMaterial) GetGuideCardMaterial(_type int) ([]GuideCardInfo, error)
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", getGuideCardMaterial, accessToken)
-	response, err := util.PostJSON(uri, map[string]interface{}{
+	response, err := g.PostJSON(uri, map[string]interface{}{
 		"type": _type,
 	})
 	if err != nil {
@@ -105,7 +110,7 @@ func (g *GuideMaterial) DelGuideCardMaterial(req DelGuideCardMaterialReq) error
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", delGuideCardMaterial, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -124,7 +129,7 @@ func (g *GuideMaterial) SetGuideImageMaterial(req SetGuideImageMaterialReq) erro
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", setGuideImageMaterial, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -146,7 +151,7 @@ func (g *GuideMaterial) GetGuideImageMaterial(_type int, start, num int) (*GetGu
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", getGuideImageMaterial, accessToken)
-	response, err := util.PostJSON(uri, map[string]interface{}{
+	response, err := g.PostJSON(uri, map[string]interface{}{
 		"type":  _type,
 		"start": start,
 		"num":   num,
@@ -177,7 +182,7 @@ func (g *GuideMaterial) DelGuideImageMaterial(req DelGuideBuyerTagReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", delGuideImageMaterial, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -197,7 +202,7 @@ func (g *GuideMaterial) SetGuideWordMaterial(req SetOrDelGuideWordMaterialReq) e
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", setGuideWordMaterial, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -222,7 +227,7 @@ func (g *GuideMaterial) GetGuideWordMaterial(_type int, start, num int) (*GetGui
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", getGuideWordMaterial, accessToken)
-	response, err := util.PostJSON(uri, map[string]interface{}{
+	response, err := g.PostJSON(uri, map[string]interface{}{
 		"type":  _type,
 		"start": start,
 		"num":   num,
@@ -248,7 +253,7 @@ func (g *GuideMaterial) DelGuideWordMaterial(req SetOrDelGuideWordMaterialReq) e
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", delGuideWordMaterial, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
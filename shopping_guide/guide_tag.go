@@ -1,6 +1,7 @@
 package shopping_guide
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 
@@ -8,7 +9,6 @@ import (
 
 	"github.com/dcsunny/wechat/common_error"
 	"github.com/dcsunny/wechat/context"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -32,6 +32,11 @@ func NewGuideTag(ctx *context.Context) *GuideTag {
 	return &GuideTag{ctx}
 }
 
+//WithContext 返回绑定了ctx的GuideTag，其发起的请求受ctx的取消和超时控制
+func (g *GuideTag) WithContext(ctx stdcontext.Context) *GuideTag {
+	return NewGuideTag(g.Context.WithContext(ctx))
+}
+
 type GuideTagInfo struct {
 	TagName   string   `json:"tag_name"`
 	TagValues []string `json:"tag_values"`
@@ -44,7 +49,7 @@ func (g *GuideTag) Add(req GuideTagInfo) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", newGuideTagOption, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -58,7 +63,7 @@ func (g *GuideTag) Delete(tagName string) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", delguidetagoption, accessToken)
-	response, err := util.PostJSON(uri, GuideTagInfo{TagName: tagName})
+	response, err := g.PostJSON(uri, GuideTagInfo{TagName: tagName})
 	if err != nil {
 		return err
 	}
@@ -72,7 +77,7 @@ func (g *GuideTag) AddGuideTagOption(req GuideTagInfo) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", addGuideTagOption, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -91,7 +96,7 @@ func (g *GuideTag) GetGuideTagOption() ([]GuideTagInfo, error) {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", getGuideTagOption, accessToken)
-	response, err := util.PostJSON(uri, nil)
+	response, err := g.PostJSON(uri, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +126,7 @@ func (g *GuideTag) AddGuideBuyerTag(req AddGuideBuyerTagReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", addGuideBuyerTag, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -146,7 +151,7 @@ func (g *GuideTag) GetGuideBuyerTag(req GetGuideBuyerTagReq) ([]string, error) {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", getGuideBuyerTag, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -180,7 +185,7 @@ func (g *GuideTag) QueryGuideBuyerByTag(req QueryGuideBuyerByTagReq) ([]string,
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", queryGuideBuyerByTag, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -210,7 +215,7 @@ func (g *GuideTag) DelGuideBuyerTag(req DelGuideBuyerTagReq) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", delGuideBuyerTag, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -231,7 +236,7 @@ func (g *GuideTag) AddGuideBuyerDisplayTag(req AddGuideBuyerDisplayTagReq) error
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", addGuideBuyerDisplayTag, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -256,7 +261,7 @@ func (g *GuideTag) GetGuideBuyerDisplayTag(req GetGuideBuyerDisplayTagReq) ([]st
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", getGuideBuyerDisplayTag, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := g.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -6,8 +6,6 @@ import (
 	"github.com/dcsunny/wechat/common_error"
 
 	"github.com/dcsunny/wechat/define"
-
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -29,7 +27,7 @@ func (tcb *Tcb) InvokeCloudFunction(env, name, args string) (*InvokeCloudFunctio
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s&env=%s&name=%s", invokeCloudFunctionURL, accessToken, env, name)
-	response, err := util.HTTPPost(uri, args)
+	response, err := tcb.HTTPPost(uri, args)
 	if err != nil {
 		return nil, err
 	}
@@ -4,8 +4,7 @@ import (
 	"fmt"
 
 	"github.com/dcsunny/wechat/common_error"
-
-	"github.com/dcsunny/wechat/util"
+	"github.com/dcsunny/wechat/define"
 )
 
 const (
@@ -64,7 +63,7 @@ type DatabaseMigrateExportReq struct {
 
 //DatabaseMigrateExportRes 数据库导出 返回结果
 type DatabaseMigrateExportRes struct {
-	common_error.CommonError
+	define.CommonError
 	JobID int64 `json:"job_id"` //导出任务ID，可使用数据库迁移进度查询 API 查询导入进度及结果
 }
 
@@ -80,13 +79,13 @@ type DatabaseMigrateImportReq struct {
 
 //DatabaseMigrateImportRes 数据库导入 返回结果
 type DatabaseMigrateImportRes struct {
-	common_error.CommonError
+	define.CommonError
 	JobID int64 `json:"job_id"` //导入任务ID，可使用数据库迁移进度查询 API 查询导入进度及结果
 }
 
 //DatabaseMigrateQueryInfoRes 数据库迁移状态查询
 type DatabaseMigrateQueryInfoRes struct {
-	common_error.CommonError
+	define.CommonError
 	Status        string `json:"status"`         //导出状态
 	RecordSuccess int64  `json:"record_success"` //导出成功记录数
 	RecordFail    int64  `json:"record_fail"`    //导出失败记录数
@@ -135,7 +134,7 @@ type DatabaseCollectionGetReq struct {
 
 //DatabaseCollectionGetRes 获取特定云环境下集合信息结果
 type DatabaseCollectionGetRes struct {
-	common_error.CommonError
+	define.CommonError
 	Pager struct {
 		Limit  int64 `json:"limit"`  //单次查询限制
 		Offset int64 `json:"offset"` //偏移量
@@ -158,19 +157,19 @@ type DatabaseReq struct {
 
 //DatabaseAddRes 数据库插入记录返回结果
 type DatabaseAddRes struct {
-	common_error.CommonError
+	define.CommonError
 	IDList []string `json:"id_list"` //插入成功的数据集合主键_id。
 }
 
 //DatabaseDeleteRes 数据库删除记录返回结果
 type DatabaseDeleteRes struct {
-	common_error.CommonError
+	define.CommonError
 	Deleted int64 `json:"deleted"` //删除记录数量
 }
 
 //DatabaseUpdateRes 数据库更新记录返回结果
 type DatabaseUpdateRes struct {
-	common_error.CommonError
+	define.CommonError
 	Matched  int64  `json:"matched"`  //更新条件匹配到的结果数
 	Modified int64  `json:"modified"` //修改的记录数，注意：使用set操作新插入的数据不计入修改数目
 	ID       string `json:"id"`
@@ -178,7 +177,7 @@ type DatabaseUpdateRes struct {
 
 //DatabaseQueryRes 数据库查询记录 返回结果
 type DatabaseQueryRes struct {
-	common_error.CommonError
+	define.CommonError
 	Pager struct {
 		Limit  int64 `json:"limit"`  //单次查询限制
 		Offset int64 `json:"offset"` //偏移量
@@ -189,7 +188,7 @@ type DatabaseQueryRes struct {
 
 //DatabaseCountRes 统计集合记录数或统计查询语句对应的结果记录数 返回结果
 type DatabaseCountRes struct {
-	common_error.CommonError
+	define.CommonError
 	Count int64 `json:"count"` //记录数量
 }
 
@@ -201,7 +200,7 @@ func (tcb *Tcb) DatabaseMigrateImport(req *DatabaseMigrateImportReq) (*DatabaseM
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseMigrateImportURL, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -218,7 +217,7 @@ func (tcb *Tcb) DatabaseMigrateExport(req *DatabaseMigrateExportReq) (*DatabaseM
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseMigrateExportURL, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -235,7 +234,7 @@ func (tcb *Tcb) DatabaseMigrateQueryInfo(env string, jobID int64) (*DatabaseMigr
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseMigrateQueryInfoURL, accessToken)
-	response, err := util.PostJSON(uri, map[string]interface{}{
+	response, err := tcb.PostJSON(uri, map[string]interface{}{
 		"env":    env,
 		"job_id": jobID,
 	})
@@ -255,7 +254,7 @@ func (tcb *Tcb) UpdateIndex(req *UpdateIndexReq) error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", updateIndexURL, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -270,7 +269,7 @@ func (tcb *Tcb) DatabaseCollectionAdd(env, collectionName string) error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseCollectionAddURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseCollectionReq{
+	response, err := tcb.PostJSON(uri, &DatabaseCollectionReq{
 		Env:            env,
 		CollectionName: collectionName,
 	})
@@ -288,7 +287,7 @@ func (tcb *Tcb) DatabaseCollectionDelete(env, collectionName string) error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseCollectionDeleteURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseCollectionReq{
+	response, err := tcb.PostJSON(uri, &DatabaseCollectionReq{
 		Env:            env,
 		CollectionName: collectionName,
 	})
@@ -306,7 +305,7 @@ func (tcb *Tcb) DatabaseCollectionGet(env string, limit, offset int64) (*Databas
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseCollectionGetURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseCollectionGetReq{
+	response, err := tcb.PostJSON(uri, &DatabaseCollectionGetReq{
 		Env:    env,
 		Limit:  limit,
 		Offset: offset,
@@ -327,7 +326,7 @@ func (tcb *Tcb) DatabaseAdd(env, query string) (*DatabaseAddRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseAddURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
@@ -347,7 +346,7 @@ func (tcb *Tcb) DatabaseDelete(env, query string) (*DatabaseDeleteRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseDeleteURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
@@ -367,7 +366,7 @@ func (tcb *Tcb) DatabaseUpdate(env, query string) (*DatabaseUpdateRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseUpdateURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
@@ -387,7 +386,7 @@ func (tcb *Tcb) DatabaseQuery(env, query string) (*DatabaseQueryRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseQueryURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
@@ -407,7 +406,7 @@ func (tcb *Tcb) DatabaseCount(env, query string) (*DatabaseCountRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseCountURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
@@ -6,8 +6,6 @@ import (
 	"github.com/dcsunny/wechat/common_error"
 
 	"github.com/dcsunny/wechat/define"
-
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -86,7 +84,7 @@ func (tcb *Tcb) UploadFile(env, path string) (*UploadFileRes, error) {
 		Env:  env,
 		Path: path,
 	}
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +105,7 @@ func (tcb *Tcb) BatchDownloadFile(env string, fileList []*DownloadFile) (*BatchD
 		Env:      env,
 		FileList: fileList,
 	}
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +126,7 @@ func (tcb *Tcb) BatchDeleteFile(env string, fileIDList []string) (*BatchDeleteFi
 		Env:        env,
 		FileIDList: fileIDList,
 	}
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,10 @@
 package tcb
 
-import "github.com/dcsunny/wechat/context"
+import (
+	stdcontext "context"
+
+	"github.com/dcsunny/wechat/context"
+)
 
 //Tcb Tencent Cloud Base
 type Tcb struct {
@@ -13,3 +17,8 @@ func NewTcb(context *context.Context) *Tcb {
 		context,
 	}
 }
+
+//WithContext 返回绑定了ctx的Tcb，其发起的请求受ctx的取消和超时控制
+func (tcb *Tcb) WithContext(ctx stdcontext.Context) *Tcb {
+	return NewTcb(tcb.Context.WithContext(ctx))
+}
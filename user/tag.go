@@ -1,6 +1,7 @@
 package user
 
 import (
+	stdcontext "context"
 	"fmt"
 
 	"github.com/dcsunny/wechat/common_error"
@@ -9,7 +10,6 @@ import (
 
 	"github.com/dcsunny/wechat/context"
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 type Tag struct {
@@ -31,6 +31,11 @@ func NewTag(context *context.Context) *Tag {
 	return tag
 }
 
+//WithContext 返回绑定了ctx的Tag，其发起的请求受ctx的取消和超时控制
+func (tag *Tag) WithContext(ctx stdcontext.Context) *Tag {
+	return NewTag(tag.Context.WithContext(ctx))
+}
+
 type CreateTagResp struct {
 	Tag TagInfo `json:"tag"`
 	define.CommonError
@@ -50,7 +55,7 @@ func (tag *Tag) CreateTag(name string) (result CreateTagResp, err error) {
 	}
 	uri := fmt.Sprintf(createTagURL, accessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, map[string]map[string]string{"tag": map[string]string{"name": name}})
+	response, err = tag.PostJSON(uri, map[string]map[string]string{"tag": map[string]string{"name": name}})
 	if err != nil {
 		return
 	}
@@ -60,7 +65,7 @@ func (tag *Tag) CreateTag(name string) (result CreateTagResp, err error) {
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("CreateTag Error , errcode=%d , errmsg=%s", result.ErrCode, result.ErrMsg)
+		err = define.NewError("CreateTag", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -79,7 +84,7 @@ func (tag *Tag) GetTags() (tags []TagInfo, err error) {
 	}
 	uri := fmt.Sprintf(getTagURL, accessToken)
 	var response []byte
-	response, err = util.HTTPGet(uri)
+	response, err = tag.HTTPGet(uri)
 	if err != nil {
 		return
 	}
@@ -90,7 +95,7 @@ func (tag *Tag) GetTags() (tags []TagInfo, err error) {
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("CreateTag Error , errcode=%d , errmsg=%s", result.ErrCode, result.ErrMsg)
+		err = define.NewError("GetTags", result.ErrCode, result.ErrMsg)
 		return
 	}
 	tags = result.Tags
@@ -109,7 +114,7 @@ func (tag *Tag) UpdateTags(req TagInfo) (err error) {
 	}
 	uri := fmt.Sprintf(updateTagURL, accessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, UpdateTagsReq{Tag: req})
+	response, err = tag.PostJSON(uri, UpdateTagsReq{Tag: req})
 	if err != nil {
 		err = fmt.Errorf("UpdateTags Error , err=%s", err)
 		return
@@ -125,7 +130,7 @@ func (tag *Tag) UpdateUserTag(openIDs []string, tagID int) (err error) {
 	}
 	uri := fmt.Sprintf(updateUserTagURL, accessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, map[string]interface{}{"openid_list": openIDs, "tagid": tagID})
+	response, err = tag.PostJSON(uri, map[string]interface{}{"openid_list": openIDs, "tagid": tagID})
 	if err != nil {
 		err = fmt.Errorf("UpdateUserTag Error , err=%s", err)
 		return
@@ -141,7 +146,7 @@ func (tag *Tag) CancelUserTag(openIDs []string, tagID int) (err error) {
 	}
 	uri := fmt.Sprintf(cancelUserTagURL, accessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, map[string]interface{}{"openid_list": openIDs, "tagid": tagID})
+	response, err = tag.PostJSON(uri, map[string]interface{}{"openid_list": openIDs, "tagid": tagID})
 	if err != nil {
 		err = fmt.Errorf("CancelUserTag Error , err=%s", err)
 		return
@@ -162,7 +167,7 @@ func (tag *Tag) GetUserTag(openID string) (tags []int, err error) {
 	}
 	uri := fmt.Sprintf(getUserTagURL, accessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, map[string]interface{}{"openid": openID})
+	response, err = tag.PostJSON(uri, map[string]interface{}{"openid": openID})
 	if err != nil {
 		err = fmt.Errorf("GetUserTag Error , err=%s", err)
 		return
@@ -173,7 +178,7 @@ func (tag *Tag) GetUserTag(openID string) (tags []int, err error) {
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserTag Error , errcode=%d , errmsg=%s", result.ErrCode, result.ErrMsg)
+		err = define.NewError("GetUserTag", result.ErrCode, result.ErrMsg)
 		return
 	}
 	tags = result.TagIDList
@@ -1,6 +1,7 @@
 package user
 
 import (
+	stdcontext "context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -9,7 +10,6 @@ import (
 
 	"github.com/dcsunny/wechat/context"
 	"github.com/dcsunny/wechat/define"
-	"github.com/dcsunny/wechat/util"
 )
 
 const (
@@ -30,6 +30,11 @@ func NewUser(context *context.Context) *User {
 	return user
 }
 
+//WithContext 返回绑定了ctx的User，其发起的请求受ctx的取消和超时控制
+func (user *User) WithContext(ctx stdcontext.Context) *User {
+	return NewUser(user.Context.WithContext(ctx))
+}
+
 //Info 用户基本信息
 type Info struct {
 	define.CommonError
@@ -73,7 +78,7 @@ func (user *User) GetUserInfo(openID string) (userInfo *Info, err error) {
 
 	uri := fmt.Sprintf(userInfoURL, accessToken, openID)
 	var response []byte
-	response, err = util.HTTPGet(uri)
+	response, err = user.HTTPGet(uri)
 	if err != nil {
 		return
 	}
@@ -83,7 +88,7 @@ func (user *User) GetUserInfo(openID string) (userInfo *Info, err error) {
 		return
 	}
 	if userInfo.ErrCode != 0 {
-		err = fmt.Errorf("GetUserInfo Error , errcode=%d , errmsg=%s", userInfo.ErrCode, userInfo.ErrMsg)
+		err = define.NewError("GetUserInfo", userInfo.ErrCode, userInfo.ErrMsg)
 		return
 	}
 	return
@@ -99,7 +104,7 @@ func (user *User) UpdateRemark(openID, remark string) (err error) {
 
 	uri := fmt.Sprintf(updateRemarkURL, accessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, map[string]string{"openid": openID, "remark": remark})
+	response, err = user.PostJSON(uri, map[string]string{"openid": openID, "remark": remark})
 	if err != nil {
 		return
 	}
@@ -122,7 +127,7 @@ func (user *User) ListUserOpenIDs(nextOpenid ...string) (*OpenidList, error) {
 	}
 	uri.RawQuery = q.Encode()
 
-	response, err := util.HTTPGet(uri.String())
+	response, err := user.HTTPGet(uri.String())
 	if err != nil {
 		return nil, err
 	}
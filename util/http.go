@@ -2,6 +2,7 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"encoding/xml"
@@ -11,12 +12,46 @@ import (
 	"mime/multipart"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
+//QuerySeparator 返回在uri后追加查询参数时使用的分隔符，uri已包含查询参数时为&
+func QuerySeparator(uri string) string {
+	if strings.Contains(uri, "?") {
+		return "&"
+	}
+	return "?"
+}
+
+//httpDo 使用client发起请求，client为nil时使用http.DefaultClient
+func httpDo(ctx context.Context, client *http.Client, method, uri, contentType string, body io.Reader) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest(method, uri, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return client.Do(req.WithContext(ctx))
+}
+
 //HTTPGet get 请求
 func HTTPGet(uri string) ([]byte, error) {
-	response, err := http.Get(uri)
+	return HTTPGetWithClient(uri, nil)
+}
+
+//HTTPGetWithClient 使用指定的client发起get请求，client为nil时使用http.DefaultClient
+func HTTPGetWithClient(uri string, client *http.Client) ([]byte, error) {
+	return HTTPGetWithContext(context.Background(), uri, client)
+}
+
+//HTTPGetWithContext 使用指定的client发起get请求，请求受ctx的取消和超时控制
+func HTTPGetWithContext(ctx context.Context, uri string, client *http.Client) ([]byte, error) {
+	response, err := httpDo(ctx, client, http.MethodGet, uri, "", nil)
 	defer func() {
 		if response != nil {
 			response.Body.Close()
@@ -34,8 +69,18 @@ func HTTPGet(uri string) ([]byte, error) {
 
 //HTTPPost post 请求
 func HTTPPost(uri string, data string) ([]byte, error) {
+	return HTTPPostWithClient(uri, data, nil)
+}
+
+//HTTPPostWithClient 使用指定的client发起post请求，client为nil时使用http.DefaultClient
+func HTTPPostWithClient(uri string, data string, client *http.Client) ([]byte, error) {
+	return HTTPPostWithContext(context.Background(), uri, data, client)
+}
+
+//HTTPPostWithContext 使用指定的client发起post请求，请求受ctx的取消和超时控制
+func HTTPPostWithContext(ctx context.Context, uri string, data string, client *http.Client) ([]byte, error) {
 	body := bytes.NewBuffer([]byte(data))
-	response, err := http.Post(uri, "", body)
+	response, err := httpDo(ctx, client, http.MethodPost, uri, "", body)
 	defer func() {
 		if response != nil {
 			response.Body.Close()
@@ -53,6 +98,16 @@ func HTTPPost(uri string, data string) ([]byte, error) {
 
 //PostJSON post json 数据请求
 func PostJSON(uri string, obj interface{}) ([]byte, error) {
+	return PostJSONWithClient(uri, obj, nil)
+}
+
+//PostJSONWithClient 使用指定的client发起post json请求，client为nil时使用http.DefaultClient
+func PostJSONWithClient(uri string, obj interface{}, client *http.Client) ([]byte, error) {
+	return PostJSONWithContext(context.Background(), uri, obj, client)
+}
+
+//PostJSONWithContext 使用指定的client发起post json请求，请求受ctx的取消和超时控制
+func PostJSONWithContext(ctx context.Context, uri string, obj interface{}, client *http.Client) ([]byte, error) {
 	jsonData, err := json.Marshal(obj)
 	if err != nil {
 		return nil, err
@@ -63,7 +118,7 @@ func PostJSON(uri string, obj interface{}) ([]byte, error) {
 	jsonData = bytes.Replace(jsonData, []byte("\\u0026"), []byte("&"), -1)
 
 	body := bytes.NewBuffer(jsonData)
-	response, err := http.Post(uri, "application/json;charset=utf-8", body)
+	response, err := httpDo(ctx, client, http.MethodPost, uri, "application/json;charset=utf-8", body)
 	defer func() {
 		if response != nil {
 			response.Body.Close()
@@ -81,6 +136,16 @@ func PostJSON(uri string, obj interface{}) ([]byte, error) {
 
 // PostJSONWithRespContentType post json数据请求，且返回数据类型
 func PostJSONWithRespContentType(uri string, obj interface{}) ([]byte, string, error) {
+	return PostJSONWithRespContentTypeWithClient(uri, obj, nil)
+}
+
+// PostJSONWithRespContentTypeWithClient 使用指定的client发起post json请求，且返回数据类型
+func PostJSONWithRespContentTypeWithClient(uri string, obj interface{}, client *http.Client) ([]byte, string, error) {
+	return PostJSONWithRespContentTypeWithContext(context.Background(), uri, obj, client)
+}
+
+//PostJSONWithRespContentTypeWithContext 使用指定的client发起post json请求，且返回数据类型，请求受ctx的取消和超时控制
+func PostJSONWithRespContentTypeWithContext(ctx context.Context, uri string, obj interface{}, client *http.Client) ([]byte, string, error) {
 	jsonData, err := json.Marshal(obj)
 	if err != nil {
 		return nil, "", err
@@ -91,7 +156,7 @@ func PostJSONWithRespContentType(uri string, obj interface{}) ([]byte, string, e
 	jsonData = bytes.Replace(jsonData, []byte("\\u0026"), []byte("&"), -1)
 
 	body := bytes.NewBuffer(jsonData)
-	response, err := http.Post(uri, "application/json;charset=utf-8", body)
+	response, err := httpDo(ctx, client, http.MethodPost, uri, "application/json;charset=utf-8", body)
 	defer func() {
 		if response != nil {
 			response.Body.Close()
@@ -121,7 +186,34 @@ func PostFile(fieldname, filename, uri string) ([]byte, error) {
 	return PostMultipartForm(fields, uri)
 }
 
+//PostFileWithClient 使用指定的client上传文件
+func PostFileWithClient(fieldname, filename, uri string, client *http.Client) ([]byte, error) {
+	return PostFileWithContext(context.Background(), fieldname, filename, uri, client)
+}
+
+//PostFileWithContext 使用指定的client上传文件，请求受ctx的取消和超时控制
+func PostFileWithContext(ctx context.Context, fieldname, filename, uri string, client *http.Client) ([]byte, error) {
+	fields := []MultipartFormField{
+		{
+			IsFile:    true,
+			Fieldname: fieldname,
+			Filename:  filename,
+		},
+	}
+	return PostMultipartFormWithContext(ctx, fields, uri, client)
+}
+
 func PostFileV2(fieldname, filename string, fileReader io.Reader, uri string) (respBody []byte, err error) {
+	return PostFileV2WithClient(fieldname, filename, fileReader, uri, nil)
+}
+
+//PostFileV2WithClient 使用指定的client上传io.Reader中的文件内容
+func PostFileV2WithClient(fieldname, filename string, fileReader io.Reader, uri string, client *http.Client) (respBody []byte, err error) {
+	return PostFileV2WithContext(context.Background(), fieldname, filename, fileReader, uri, client)
+}
+
+//PostFileV2WithContext 使用指定的client上传io.Reader中的文件内容，请求受ctx的取消和超时控制
+func PostFileV2WithContext(ctx context.Context, fieldname, filename string, fileReader io.Reader, uri string, client *http.Client) (respBody []byte, err error) {
 	field := MultipartFormField{
 		IsFile:    true,
 		Fieldname: fieldname,
@@ -143,7 +235,7 @@ func PostFileV2(fieldname, filename string, fileReader io.Reader, uri string) (r
 	contentType := bodyWriter.FormDataContentType()
 	bodyWriter.Close()
 
-	resp, e := http.Post(uri, contentType, bodyBuf)
+	resp, e := httpDo(ctx, client, http.MethodPost, uri, contentType, bodyBuf)
 	defer func() {
 		if resp != nil {
 			resp.Body.Close()
@@ -170,6 +262,16 @@ type MultipartFormField struct {
 
 //PostMultipartForm 上传文件或其他多个字段
 func PostMultipartForm(fields []MultipartFormField, uri string) (respBody []byte, err error) {
+	return PostMultipartFormWithClient(fields, uri, nil)
+}
+
+//PostMultipartFormWithClient 使用指定的client上传文件或其他多个字段
+func PostMultipartFormWithClient(fields []MultipartFormField, uri string, client *http.Client) (respBody []byte, err error) {
+	return PostMultipartFormWithContext(context.Background(), fields, uri, client)
+}
+
+//PostMultipartFormWithContext 使用指定的client上传文件或其他多个字段，请求受ctx的取消和超时控制
+func PostMultipartFormWithContext(ctx context.Context, fields []MultipartFormField, uri string, client *http.Client) (respBody []byte, err error) {
 	bodyBuf := &bytes.Buffer{}
 	bodyWriter := multipart.NewWriter(bodyBuf)
 
@@ -214,7 +316,7 @@ func PostMultipartForm(fields []MultipartFormField, uri string) (respBody []byte
 	contentType := bodyWriter.FormDataContentType()
 	bodyWriter.Close()
 
-	resp, e := http.Post(uri, contentType, bodyBuf)
+	resp, e := httpDo(ctx, client, http.MethodPost, uri, contentType, bodyBuf)
 	if e != nil {
 		err = e
 		return
@@ -229,16 +331,18 @@ func PostMultipartForm(fields []MultipartFormField, uri string) (respBody []byte
 
 //PostXML perform a HTTP/POST request with XML body
 func PostXML(uri string, obj interface{}, client *http.Client) ([]byte, error) {
+	return PostXMLWithContext(context.Background(), uri, obj, client)
+}
+
+//PostXMLWithContext perform a HTTP/POST request with XML body, the request is bound to ctx
+func PostXMLWithContext(ctx context.Context, uri string, obj interface{}, client *http.Client) ([]byte, error) {
 	xmlData, err := xml.Marshal(obj)
 	if err != nil {
 		return nil, err
 	}
 	body := bytes.NewBuffer(xmlData)
-	if client == nil {
-		client = http.DefaultClient
-	}
 	var response *http.Response
-	response, err = client.Post(uri, "application/xml;charset=utf-8", body)
+	response, err = httpDo(ctx, client, http.MethodPost, uri, "application/xml;charset=utf-8", body)
 	defer func() {
 		if response != nil {
 			response.Body.Close()
@@ -1,6 +1,7 @@
 package wechat
 
 import (
+	stdcontext "context"
 	"net/http"
 	"sync"
 
@@ -39,10 +40,20 @@ type Config struct {
 	PayMchID        string //支付 - 商户 ID
 	PayNotifyURL    string //支付 - 接受微信支付结果通知的接口地址
 	PayKey          string //支付 - 商户后台设置的支付 key
-	AccessTokenURL  string
+	AccessTokenURL  string //获取access_token的地址，可指向relay中控服务
+	JsAPITicketURL  string //获取jsapi_ticket的地址，可指向relay中控服务
 	PayCertPEMBlock string
 	PayKeyPEMBlock  string
 	Cache           cache.Cache
+	Locker          cache.Locker //多实例部署时刷新access_token等凭据使用的分布式锁，为空且Cache实现了cache.Locker时使用Cache
+
+	HTTPClient *http.Client //调用微信接口使用的client，可设置超时、代理等，默认http.DefaultClient
+	APIHost    string       //微信接口域名，默认https://api.weixin.qq.com
+	PayAPIHost string       //微信支付接口域名，默认https://api.mch.weixin.qq.com
+
+	StableAccessToken bool //使用稳定版接口(cgi-bin/stable_token)获取access_token，多个服务共用AppID时不会使彼此的token失效；设置了AccessTokenURL时不生效，从AccessTokenURL获取
+
+	ComponentStore context.ComponentStore //第三方平台保存component_verify_ticket及授权方refresh_token的存储，默认使用Cache
 }
 
 // NewWechat init
@@ -63,23 +74,60 @@ func copyConfigToContext(cfg *Config, context *context.Context) {
 	context.PayCertPEMBlock = cfg.PayCertPEMBlock
 	context.PayKeyPEMBlock = cfg.PayKeyPEMBlock
 	context.Cache = cfg.Cache
+	context.Locker = cfg.Locker
+	if context.Locker == nil {
+		if locker, ok := cfg.Cache.(cache.Locker); ok {
+			context.Locker = locker
+		}
+	}
 	context.AccessTokenURL = cfg.AccessTokenURL
+	context.JsAPITicketURL = cfg.JsAPITicketURL
+	context.StableAccessToken = cfg.StableAccessToken
+	context.ComponentStore = cfg.ComponentStore
+	context.HTTPClient = cfg.HTTPClient
+	context.APIHost = cfg.APIHost
+	context.PayAPIHost = cfg.PayAPIHost
 	context.SetAccessTokenLock(new(sync.RWMutex))
 	context.SetJsAPITicketLock(new(sync.RWMutex))
+	context.SetAuthorizerLocks(new(sync.Map))
 }
 
-// GetServer 消息管理
+// GetServer 消息管理，返回处理单个请求的Server
 func (wc *Wechat) GetServer(req *http.Request, writer http.ResponseWriter) *server.Server {
-	wc.Context.Request = req
-	wc.Context.Writer = writer
+	return server.NewRequestServer(wc.Context, req, writer)
+}
+
+// NewServer 消息管理，返回的Server实现了http.Handler，可并发处理所有请求
+func (wc *Wechat) NewServer() *server.Server {
 	return server.NewServer(wc.Context)
 }
 
+// NewComponentServer 第三方平台的消息与事件接收服务，按授权方分发消息，wc需使用第三方平台的配置创建
+func (wc *Wechat) NewComponentServer() *server.ComponentServer {
+	return server.NewComponentServer(wc.Context)
+}
+
+//Authorizer 第三方平台代授权方appid调用接口，返回的Wechat使用授权方的authorizer_access_token(过期时自动刷新)调用所有接口，
+//wc需使用第三方平台的component_appid、component_appsecret创建
+func (wc *Wechat) Authorizer(appid string) *Wechat {
+	return &Wechat{wc.Context.AuthorizerContext(appid)}
+}
+
+//WithContext 返回绑定了ctx的Wechat，通过它获取的各接口实例发起的请求都受ctx的取消和超时控制
+func (wc *Wechat) WithContext(ctx stdcontext.Context) *Wechat {
+	return &Wechat{wc.Context.WithContext(ctx)}
+}
+
 //GetAccessToken 获取access_token
 func (wc *Wechat) GetAccessToken() (string, error) {
 	return wc.Context.GetAccessToken()
 }
 
+//GetAccessTokenWithContext 获取access_token，请求受ctx的取消和超时控制
+func (wc *Wechat) GetAccessTokenWithContext(ctx stdcontext.Context) (string, error) {
+	return wc.Context.GetAccessTokenWithContext(ctx)
+}
+
 // GetOauth oauth2网页授权
 func (wc *Wechat) GetOauth() *oauth.Oauth {
 	return oauth.NewOauth(wc.Context)
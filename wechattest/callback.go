@@ -0,0 +1,283 @@
+package wechattest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dcsunny/wechat/message"
+	"github.com/dcsunny/wechat/util"
+)
+
+//DefaultToUserName 未设置ToUserName时使用的公众号原始ID
+const DefaultToUserName = "gh_wechattest"
+
+var msgSeq = time.Now().UnixNano()
+
+//Callback 按照微信的方式构造消息推送请求：计算signature，配置了EncodingAESKey时使用安全模式加密并计算msg_signature
+type Callback struct {
+	Token string
+	//EncodingAESKey 不为空时使用安全模式
+	EncodingAESKey string
+	//AppID 安全模式下加密使用的AppID
+	AppID string
+	//ToUserName 消息中未设置ToUserName时填充，默认为DefaultToUserName
+	ToUserName string
+	//JSON 使用JSON格式推送，用于小程序
+	JSON bool
+	//Client Post使用的http.Client，默认为http.DefaultClient
+	Client *http.Client
+}
+
+//TextMessage 返回openID发送的文本消息
+func TextMessage(openID, content string) message.MixMessage {
+	msg := newMessage(openID, message.MsgTypeText)
+	msg.MsgID = atomic.AddInt64(&msgSeq, 1)
+	msg.Content = content
+	return msg
+}
+
+//EventMessage 返回openID触发的事件推送，如关注、扫码、菜单点击等
+func EventMessage(openID string, event message.EventType, eventKey string) message.MixMessage {
+	msg := newMessage(openID, message.MsgTypeEvent)
+	msg.Event = event
+	msg.EventKey = eventKey
+	return msg
+}
+
+func newMessage(openID string, msgType message.MsgType) message.MixMessage {
+	var msg message.MixMessage
+	msg.FromUserName = message.CDATA(openID)
+	msg.CreateTime = time.Now().Unix()
+	msg.MsgType = msgType
+	return msg
+}
+
+//NewRequest 构造推送msg到target的请求，msg可以是message.MixMessage、其他消息结构体，或者[]byte、string格式的原始消息
+func (c *Callback) NewRequest(target string, msg interface{}) (*http.Request, error) {
+	body, err := c.encodeMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := util.RandomStr(10)
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("nonce", nonce)
+	query.Set("signature", util.Signature(c.Token, timestamp, nonce))
+	if mixMsg, ok := asMixMessage(msg); ok {
+		query.Set("openid", string(mixMsg.FromUserName))
+	}
+
+	if c.EncodingAESKey != "" {
+		encrypted, err := util.EncryptMsg([]byte(util.RandomStr(16)), body, c.AppID, c.EncodingAESKey)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("encrypt_type", "aes")
+		query.Set("msg_signature", util.Signature(c.Token, timestamp, nonce, string(encrypted)))
+		body, err = c.marshal(message.EncryptedXMLMsg{ToUserName: c.toUserName(), EncryptedMsg: string(encrypted)})
+		if err != nil {
+			return nil, err
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.JSON {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "text/xml")
+	}
+	return req, nil
+}
+
+//Do 使用handler(通常为server.Server)处理msg的推送请求，返回解密后的回复
+func (c *Callback) Do(handler http.Handler, msg interface{}) (*CallbackReply, error) {
+	req, err := c.NewRequest("http://localhost/", msg)
+	if err != nil {
+		return nil, err
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return c.ParseReply(recorder.Code, recorder.Body.Bytes())
+}
+
+//Post 将msg推送到target，返回解密后的回复
+func (c *Callback) Post(target string, msg interface{}) (*CallbackReply, error) {
+	req, err := c.NewRequest(target, msg)
+	if err != nil {
+		return nil, err
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return c.ParseReply(resp.StatusCode, body)
+}
+
+//ParseReply 校验并解密安全模式下的回复
+func (c *Callback) ParseReply(statusCode int, body []byte) (*CallbackReply, error) {
+	reply := &CallbackReply{StatusCode: statusCode, Body: body}
+	if statusCode != http.StatusOK {
+		return reply, fmt.Errorf("http status %d: %s", statusCode, body)
+	}
+	trimmed := strings.TrimSpace(string(body))
+	if trimmed == "" || trimmed == "success" {
+		return reply, nil
+	}
+	if c.EncodingAESKey != "" {
+		var encrypted message.ResponseEncryptedXMLMsg
+		if err := c.unmarshal(body, &encrypted); err != nil {
+			return reply, fmt.Errorf("解析加密回复失败, err=%v", err)
+		}
+		timestamp := strconv.FormatInt(encrypted.Timestamp, 10)
+		if encrypted.MsgSignature != util.Signature(c.Token, timestamp, encrypted.Nonce, encrypted.EncryptedMsg) {
+			return reply, fmt.Errorf("回复的msg_signature不正确")
+		}
+		_, raw, err := util.DecryptMsg(c.AppID, encrypted.EncryptedMsg, c.EncodingAESKey)
+		if err != nil {
+			return reply, fmt.Errorf("回复解密失败, err=%v", err)
+		}
+		reply.Body = raw
+	}
+	var common message.CommonToken
+	if err := c.unmarshal(reply.Body, &common); err != nil {
+		return reply, fmt.Errorf("解析回复失败, err=%v", err)
+	}
+	reply.MsgType = common.MsgType
+	reply.json = c.JSON
+	return reply, nil
+}
+
+func (c *Callback) encodeMessage(msg interface{}) ([]byte, error) {
+	switch raw := msg.(type) {
+	case []byte:
+		return raw, nil
+	case string:
+		return []byte(raw), nil
+	}
+	if mixMsg, ok := asMixMessage(msg); ok {
+		if mixMsg.ToUserName == "" {
+			mixMsg.ToUserName = message.CDATA(c.toUserName())
+		}
+		msg = mixMsg
+	}
+	return c.marshal(msg)
+}
+
+func asMixMessage(msg interface{}) (message.MixMessage, bool) {
+	switch m := msg.(type) {
+	case message.MixMessage:
+		return m, true
+	case *message.MixMessage:
+		return *m, true
+	}
+	return message.MixMessage{}, false
+}
+
+func (c *Callback) toUserName() string {
+	if c.ToUserName != "" {
+		return c.ToUserName
+	}
+	return DefaultToUserName
+}
+
+func (c *Callback) marshal(v interface{}) ([]byte, error) {
+	if c.JSON {
+		return json.Marshal(v)
+	}
+	return xml.Marshal(v)
+}
+
+func (c *Callback) unmarshal(data []byte, v interface{}) error {
+	if c.JSON {
+		return json.Unmarshal(data, v)
+	}
+	return xml.Unmarshal(data, v)
+}
+
+//CallbackReply 解密后的回复，没有回复或回复success时MsgType为空
+type CallbackReply struct {
+	StatusCode int
+	//Body 回复的明文
+	Body    []byte
+	MsgType message.MsgType
+
+	json bool
+}
+
+//Decode 将回复解析到v中，如*message.Text
+func (r *CallbackReply) Decode(v interface{}) error {
+	if r.json {
+		return json.Unmarshal(r.Body, v)
+	}
+	return xml.Unmarshal(r.Body, v)
+}
+
+//Message 按MsgType将回复解析为*message.Text、*message.Image、*message.Voice、*message.Video、
+//*message.Music、*message.News或*message.TransferCustomer，没有回复时返回nil
+func (r *CallbackReply) Message() (interface{}, error) {
+	var msg interface{}
+	switch r.MsgType {
+	case "":
+		return nil, nil
+	case message.MsgTypeText:
+		msg = new(message.Text)
+	case message.MsgTypeImage:
+		msg = new(message.Image)
+	case message.MsgTypeVoice:
+		msg = new(message.Voice)
+	case message.MsgTypeVideo:
+		msg = new(message.Video)
+	case message.MsgTypeMusic:
+		msg = new(message.Music)
+	case message.MsgTypeNews:
+		msg = new(message.News)
+	case message.MsgTypeTransfer:
+		msg = new(message.TransferCustomer)
+	default:
+		return nil, message.ErrUnsupportReply
+	}
+	if err := r.Decode(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+//Text 返回文本回复的内容，回复不是文本消息时返回错误
+func (r *CallbackReply) Text() (string, error) {
+	if r.MsgType != message.MsgTypeText {
+		return "", fmt.Errorf("reply msgtype is %q, not text", r.MsgType)
+	}
+	var text message.Text
+	if err := r.Decode(&text); err != nil {
+		return "", err
+	}
+	return string(text.Content), nil
+}
@@ -0,0 +1,50 @@
+package wechattest
+
+import (
+	"testing"
+
+	"github.com/dcsunny/wechat"
+	"github.com/dcsunny/wechat/message"
+)
+
+func TestCallback(t *testing.T) {
+	const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	wc := wechat.NewWechat(&wechat.Config{AppID: "appid", Token: "token", EncodingAESKey: aesKey})
+	srv := wc.NewServer()
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		if msg.MsgType == message.MsgTypeEvent {
+			news := message.NewNews([]*message.Article{message.NewArticle("welcome", "", "", "https://example.com")})
+			return &message.Reply{MsgType: message.MsgTypeNews, MsgData: news}
+		}
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("echo " + msg.Content)}
+	})
+
+	for _, c := range []*Callback{
+		{Token: "token"},
+		{Token: "token", AppID: "appid", EncodingAESKey: aesKey},
+		{Token: "token", AppID: "appid", EncodingAESKey: aesKey, JSON: true},
+	} {
+		reply, err := c.Do(srv, TextMessage("openid", "hi"))
+		if err != nil {
+			t.Fatalf("safe=%v json=%v: %v", c.EncodingAESKey != "", c.JSON, err)
+		}
+		if text, err := reply.Text(); err != nil || text != "echo hi" {
+			t.Errorf("safe=%v json=%v: unexpected reply %q %v", c.EncodingAESKey != "", c.JSON, text, err)
+		}
+	}
+
+	c := &Callback{Token: "token", AppID: "appid", EncodingAESKey: aesKey}
+	reply, err := c.Do(srv, EventMessage("openid", message.EventSubscribe, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := reply.Message()
+	news, ok := msg.(*message.News)
+	if err != nil || !ok || len(news.Articles) != 1 || news.Articles[0].Title != "welcome" || news.ToUserName != "openid" {
+		t.Errorf("unexpected news reply %+v %v", msg, err)
+	}
+
+	if _, err := (&Callback{Token: "wrong"}).Do(srv, TextMessage("openid", "hi")); err == nil {
+		t.Error("expect error for invalid signature")
+	}
+}
@@ -0,0 +1,211 @@
+package wechattest
+
+import (
+	"encoding/xml"
+	"net/http"
+	"time"
+)
+
+const (
+	//JsAPITicket 默认返回的jsapi_ticket
+	JsAPITicket = "JSAPI_TICKET"
+	//MediaID 默认返回的素材media_id
+	MediaID = "MEDIA_ID"
+	//QRTicket 默认返回的二维码ticket
+	QRTicket = "QR_TICKET"
+	//PrepayID 默认返回的预支付交易会话标识
+	PrepayID = "PREPAY_ID"
+	//ComponentAccessToken 默认返回的component_access_token
+	ComponentAccessToken = "COMPONENT_ACCESS_TOKEN"
+	//PreAuthCode 默认返回的预授权码
+	PreAuthCode = "PRE_AUTH_CODE"
+	//AuthorizerAppID 使用授权码换取授权信息时默认返回的授权方appid
+	AuthorizerAppID = "wxauthorizerappid"
+	//AuthorizerRefreshToken 默认返回的authorizer_refresh_token
+	AuthorizerRefreshToken = "AUTHORIZER_REFRESH_TOKEN"
+)
+
+// Image 小程序码、临时素材等接口默认返回的图片内容
+var Image = []byte("\xff\xd8\xff\xe0JFIF")
+
+// payResponse 微信支付接口的通用成功响应
+type payResponse struct {
+	XMLName    xml.Name `xml:"xml"`
+	ReturnCode string   `xml:"return_code"`
+	ResultCode string   `xml:"result_code"`
+	PrepayID   string   `xml:"prepay_id,omitempty"`
+	TradeType  string   `xml:"trade_type,omitempty"`
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+}
+
+func imageHandler(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, Response{ContentType: "image/jpeg", Body: Image})
+}
+
+func (s *Server) registerDefaults() {
+	s.noTokenPath = map[string]bool{
+		"/cgi-bin/token":                         true,
+		"/cgi-bin/stable_token":                  true,
+		"/sns/jscode2session":                    true,
+		"/pay/unifiedorder":                      true,
+		"/secapi/pay/refund":                     true,
+		"/mmpaymkttransfers/promotion/transfers": true,
+		"/mmpaymkttransfers/sendredpack":         true,
+
+		"/cgi-bin/component/api_component_token":       true,
+		"/cgi-bin/component/api_create_preauthcode":    true,
+		"/cgi-bin/component/api_query_auth":            true,
+		"/cgi-bin/component/api_authorizer_token":      true,
+		"/cgi-bin/component/api_get_authorizer_info":   true,
+		"/cgi-bin/component/api_get_authorizer_list":   true,
+		"/cgi-bin/component/api_get_authorizer_option": true,
+		"/cgi-bin/component/api_set_authorizer_option": true,
+		"/cgi-bin/component/clear_quota":               true,
+	}
+	s.defaultMux = map[string]http.HandlerFunc{
+		//access_token、jsapi_ticket
+		"/cgi-bin/token":        s.token,
+		"/cgi-bin/stable_token": s.token,
+		"/cgi-bin/ticket/getticket": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "ticket": JsAPITicket, "expires_in": 7200})
+		},
+
+		//自定义菜单
+		"/cgi-bin/menu/create":         okHandler,
+		"/cgi-bin/menu/delete":         okHandler,
+		"/cgi-bin/menu/delconditional": okHandler,
+		"/cgi-bin/menu/addconditional": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"menuid": 1})
+		},
+		"/cgi-bin/menu/get": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"menu": map[string]interface{}{"button": []interface{}{}}})
+		},
+		"/cgi-bin/menu/trymatch": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"button": []interface{}{}})
+		},
+		"/cgi-bin/get_current_selfmenu_info": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"is_menu_open": 1, "selfmenu_info": map[string]interface{}{"button": []interface{}{}}})
+		},
+
+		//用户管理
+		"/cgi-bin/user/info": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"subscribe":      1,
+				"openid":         r.URL.Query().Get("openid"),
+				"nickname":       "nickname",
+				"language":       "zh_CN",
+				"subscribe_time": time.Now().Unix(),
+				"tagid_list":     []int{},
+			})
+		},
+		"/cgi-bin/user/info/updateremark": okHandler,
+		"/cgi-bin/user/get": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"total":       1,
+				"count":       1,
+				"data":        map[string]interface{}{"openid": []string{"OPENID"}},
+				"next_openid": "",
+			})
+		},
+
+		//模板消息
+		"/cgi-bin/message/template/send": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "msgid": 1})
+		},
+
+		//永久素材
+		"/cgi-bin/material/add_news": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"media_id": MediaID})
+		},
+		"/cgi-bin/material/add_material": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"media_id": MediaID, "url": s.URL + "/material/" + MediaID})
+		},
+		"/cgi-bin/material/del_material": okHandler,
+
+		//临时素材
+		"/cgi-bin/media/upload": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"type": r.URL.Query().Get("type"), "media_id": MediaID, "created_at": time.Now().Unix()})
+		},
+		"/cgi-bin/media/uploadimg": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"url": s.URL + "/mmbiz/" + MediaID})
+		},
+		"/cgi-bin/media/get": imageHandler,
+
+		//二维码
+		"/cgi-bin/qrcode/create": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"ticket": QRTicket, "expire_seconds": 2592000, "url": "http://weixin.qq.com/q/" + QRTicket})
+		},
+
+		//小程序
+		"/sns/jscode2session": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"openid": "OPENID", "session_key": "SESSION_KEY"})
+		},
+		"/cgi-bin/wxaapp/createwxaqrcode": imageHandler,
+		"/wxa/getwxacode":                 imageHandler,
+		"/wxa/getwxacodeunlimit":          imageHandler,
+
+		//微信支付
+		"/pay/unifiedorder": func(w http.ResponseWriter, r *http.Request) {
+			writeXML(w, payResponse{ReturnCode: "SUCCESS", ResultCode: "SUCCESS", PrepayID: PrepayID, TradeType: "JSAPI"})
+		},
+		"/secapi/pay/refund":                     payOK,
+		"/mmpaymkttransfers/promotion/transfers": payOK,
+		"/mmpaymkttransfers/sendredpack":         payOK,
+
+		//第三方平台，授权方的authorizer_access_token与AccessToken()相同
+		"/cgi-bin/component/api_component_token": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"component_access_token": ComponentAccessToken, "expires_in": 7200})
+		},
+		"/cgi-bin/component/api_create_preauthcode": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"pre_auth_code": PreAuthCode, "expires_in": 600})
+		},
+		"/cgi-bin/component/api_query_auth": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"authorization_info": map[string]interface{}{
+				"authorizer_appid":         AuthorizerAppID,
+				"authorizer_access_token":  s.AccessToken(),
+				"expires_in":               7200,
+				"authorizer_refresh_token": AuthorizerRefreshToken,
+				"func_info":                []interface{}{},
+			}})
+		},
+		"/cgi-bin/component/api_authorizer_token": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"authorizer_access_token":  s.AccessToken(),
+				"expires_in":               7200,
+				"authorizer_refresh_token": AuthorizerRefreshToken,
+			})
+		},
+		"/cgi-bin/component/api_get_authorizer_info": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"authorizer_info":    map[string]interface{}{"nick_name": "nickname", "user_name": "gh_authorizer"},
+				"authorization_info": map[string]interface{}{"authorizer_appid": AuthorizerAppID, "func_info": []interface{}{}},
+			})
+		},
+		"/cgi-bin/component/api_get_authorizer_list": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"total_count": 1,
+				"list": []interface{}{map[string]interface{}{
+					"authorizer_appid": AuthorizerAppID,
+					"refresh_token":    AuthorizerRefreshToken,
+					"auth_time":        time.Now().Unix(),
+				}},
+			})
+		},
+		"/cgi-bin/component/api_get_authorizer_option": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"authorizer_appid": AuthorizerAppID, "option_name": "voice_recognize", "option_value": "1"})
+		},
+		"/cgi-bin/component/api_set_authorizer_option": okHandler,
+		"/cgi-bin/component/clear_quota":               okHandler,
+	}
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{"access_token": s.AccessToken(), "expires_in": 7200})
+}
+
+func payOK(w http.ResponseWriter, r *http.Request) {
+	writeXML(w, payResponse{ReturnCode: "SUCCESS", ResultCode: "SUCCESS"})
+}
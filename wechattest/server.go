@@ -0,0 +1,214 @@
+//Package wechattest 提供离线模拟微信接口的httptest.Server，用于在测试中替代api.weixin.qq.com和api.mch.weixin.qq.com
+package wechattest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	"github.com/dcsunny/wechat"
+)
+
+//Response 脚本化的响应
+type Response struct {
+	//StatusCode 默认200
+	StatusCode int
+	//ContentType 为空时[]byte、string原样返回为text/plain，其他类型按JSON编码
+	ContentType string
+	//Body []byte、string原样返回，其他类型按JSON编码
+	Body interface{}
+}
+
+//Error 返回指定errcode的JSON响应
+func Error(errCode int64, errMsg string) Response {
+	return Response{Body: map[string]interface{}{"errcode": errCode, "errmsg": errMsg}}
+}
+
+//PayError 返回微信支付业务失败(result_code=FAIL)的XML响应
+func PayError(errCode, errCodeDes string) Response {
+	return Response{
+		ContentType: "text/xml",
+		Body:        fmt.Sprintf("<xml><return_code>SUCCESS</return_code><result_code>FAIL</result_code><err_code>%s</err_code><err_code_des>%s</err_code_des></xml>", errCode, errCodeDes),
+	}
+}
+
+//Request 记录收到的请求
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   []byte
+}
+
+//Server 模拟微信接口的服务，默认实现了access_token、jsapi_ticket、菜单、用户、模板消息、素材、临时素材、
+//二维码、小程序码及微信支付等接口，其余路径返回errcode=0，可通过Enqueue、SetResponse、Handle修改
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	tokenSeq    int
+	handlers    map[string]http.HandlerFunc
+	queued      map[string][]Response
+	responses   map[string]Response
+	requests    []Request
+	defaultMux  map[string]http.HandlerFunc
+	noTokenPath map[string]bool
+}
+
+//NewServer 启动模拟服务，使用完毕后调用Close
+func NewServer() *Server {
+	s := &Server{
+		tokenSeq:  1,
+		handlers:  make(map[string]http.HandlerFunc),
+		queued:    make(map[string][]Response),
+		responses: make(map[string]Response),
+	}
+	s.registerDefaults()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+//Apply 将cfg的接口域名及HTTPClient指向模拟服务并返回cfg
+func (s *Server) Apply(cfg *wechat.Config) *wechat.Config {
+	cfg.APIHost = s.URL
+	cfg.PayAPIHost = s.URL
+	cfg.HTTPClient = s.Client()
+	return cfg
+}
+
+//NewWechat 使用cfg创建指向模拟服务的Wechat
+func (s *Server) NewWechat(cfg *wechat.Config) *wechat.Wechat {
+	return wechat.NewWechat(s.Apply(cfg))
+}
+
+//AccessToken 返回当前有效的access_token
+func (s *Server) AccessToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accessToken()
+}
+
+func (s *Server) accessToken() string {
+	return fmt.Sprintf("ACCESS_TOKEN_%d", s.tokenSeq)
+}
+
+//InvalidateAccessToken 使当前access_token失效，之后携带它的请求返回40001，重新获取时得到新的token
+func (s *Server) InvalidateAccessToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenSeq++
+}
+
+//Enqueue 为path依次返回responses，用完后恢复原来的响应
+func (s *Server) Enqueue(path string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued[path] = append(s.queued[path], responses...)
+}
+
+//SetResponse 之后对path的请求都返回resp
+func (s *Server) SetResponse(path string, resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[path] = resp
+}
+
+//Handle 使用handler处理path的请求，替代默认实现(不再校验access_token)
+func (s *Server) Handle(path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[path] = handler
+}
+
+//Reset 清除path上通过Enqueue、SetResponse、Handle设置的响应
+func (s *Server) Reset(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.queued, path)
+	delete(s.responses, path)
+	delete(s.handlers, path)
+}
+
+//Requests 返回收到的path请求，path为空时返回全部请求
+func (s *Server) Requests(path string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []Request
+	for _, req := range s.requests {
+		if path == "" || req.Path == path {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	path := r.URL.Path
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: path, Query: r.URL.Query(), Body: body})
+	handler, hasHandler := s.handlers[path]
+	validToken := s.noTokenPath[path] || r.URL.Query().Get("access_token") == s.accessToken()
+	var resp *Response
+	if queued := s.queued[path]; len(queued) > 0 && validToken {
+		resp = &queued[0]
+		s.queued[path] = queued[1:]
+	} else if stored, ok := s.responses[path]; ok && validToken {
+		resp = &stored
+	}
+	s.mu.Unlock()
+
+	switch {
+	case hasHandler:
+		handler(w, r)
+	case !validToken:
+		writeResponse(w, Error(40001, "invalid credential, access_token is invalid or not latest"))
+	case resp != nil:
+		writeResponse(w, *resp)
+	case s.defaultMux[path] != nil:
+		s.defaultMux[path](w, r)
+	default:
+		writeResponse(w, Error(0, "ok"))
+	}
+}
+
+func writeResponse(w http.ResponseWriter, resp Response) {
+	var data []byte
+	contentType := resp.ContentType
+	switch body := resp.Body.(type) {
+	case []byte:
+		data = body
+	case string:
+		data = []byte(body)
+	default:
+		data, _ = json.Marshal(body)
+		if contentType == "" {
+			contentType = "application/json; encoding=utf-8"
+		}
+	}
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if resp.StatusCode != 0 {
+		w.WriteHeader(resp.StatusCode)
+	}
+	w.Write(data)
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	writeResponse(w, Response{Body: body})
+}
+
+func writeXML(w http.ResponseWriter, body interface{}) {
+	data, _ := xml.Marshal(body)
+	writeResponse(w, Response{ContentType: "text/xml", Body: data})
+}
@@ -0,0 +1,66 @@
+package wechattest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/dcsunny/wechat"
+	"github.com/dcsunny/wechat/cache"
+	"github.com/dcsunny/wechat/define"
+	"github.com/dcsunny/wechat/menu"
+	"github.com/dcsunny/wechat/miniprogram"
+	"github.com/dcsunny/wechat/pay"
+	"github.com/dcsunny/wechat/qr"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	wc := s.NewWechat(&wechat.Config{AppID: "appid", AppSecret: "secret", Cache: cache.NewMemory()})
+
+	if err := wc.GetMenu().SetMenu([]*menu.Button{}); err != nil {
+		t.Errorf("SetMenu: %v", err)
+	}
+	s.Enqueue("/cgi-bin/menu/create", Error(45009, "reach max api daily quota limit"))
+	if err := wc.GetMenu().SetMenu([]*menu.Button{}); !errors.Is(err, define.ErrQuotaExceeded) {
+		t.Errorf("expect scripted errcode, got %v", err)
+	}
+	if err := wc.GetMenu().SetMenu([]*menu.Button{}); err != nil {
+		t.Errorf("expect default response after queue drained, got %v", err)
+	}
+
+	s.InvalidateAccessToken()
+	userInfo, err := wc.GetUser().GetUserInfo("OPENID")
+	if err != nil || userInfo.OpenID != "OPENID" {
+		t.Errorf("expect retry with new token, got %v %v", userInfo, err)
+	}
+	if n := len(s.Requests("/cgi-bin/token")); n != 2 {
+		t.Errorf("expect 2 token requests, got %d", n)
+	}
+
+	ticket, err := wc.GetQrCode().GetQRTicket(&qr.Request{ActionName: "QR_LIMIT_STR_SCENE"})
+	if err != nil || ticket.Ticket != QRTicket {
+		t.Errorf("GetQRTicket: %v %v", ticket, err)
+	}
+
+	code, err := wc.GetMiniProgram().GetWXACode(miniprogram.QRCoder{Path: "pages/index"})
+	if err != nil || !bytes.Equal(code, Image) {
+		t.Errorf("GetWXACode: %v", err)
+	}
+
+	prepayID, err := wc.GetPay().PrePayId(&pay.Params{TotalFee: 1, OutTradeNo: "order", TradeType: "JSAPI"})
+	if err != nil || prepayID != PrepayID {
+		t.Errorf("PrePayId: %s %v", prepayID, err)
+	}
+	s.Enqueue("/pay/unifiedorder", PayError("ORDERPAID", "order paid"))
+	_, err = wc.GetPay().PrePayId(&pay.Params{TotalFee: 1, OutTradeNo: "order", TradeType: "JSAPI"})
+	var apiErr *define.Error
+	if !errors.As(err, &apiErr) || apiErr.PayErrCode != "ORDERPAID" {
+		t.Errorf("expect scripted pay error, got %v", err)
+	}
+	s.Enqueue("/pay/unifiedorder", PayError("SYSTEMERROR", "system error"))
+	if _, err := wc.GetPay().PrePayId(&pay.Params{TotalFee: 1, OutTradeNo: "order", TradeType: "JSAPI"}); !errors.Is(err, define.ErrSystemBusy) {
+		t.Errorf("expect ErrSystemBusy, got %v", err)
+	}
+}